@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/antflydb/shopify-app-template-go/config"
+	"github.com/antflydb/shopify-app-template-go/internal/app"
+)
+
+func main() {
+	app.ReencryptAccessTokens(config.Get())
+}
@@ -3,17 +3,20 @@ package config
 import (
 	"log"
 	"sync"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type (
 	Config struct {
-		App      App
-		Shopify  Shopify
-		HTTP     HTTP
-		Log      Log
-		Database DatabaseConfig
+		App        App
+		Shopify    Shopify
+		HTTP       HTTP
+		Log        Log
+		Database   DatabaseConfig
+		Webhooks   Webhooks
+		Encryption Encryption
 	}
 
 	App struct {
@@ -23,9 +26,35 @@ type (
 	}
 
 	Shopify struct {
-		ApiKey    string `env:"SHOPIFY_API_KEY" env-default:""`
-		ApiSecret string `env:"SHOPIFY_API_SECRET" env-default:""`
-		Scopes    string `env:"SCOPES" env-default:""`
+		ApiKey        string        `env:"SHOPIFY_API_KEY" env-default:""`
+		ApiSecret     string        `env:"SHOPIFY_API_SECRET" env-default:""`
+		Scopes        string        `env:"SCOPES" env-default:""`
+		RequestMaxAge time.Duration `env:"SHOPIFY_REQUEST_MAX_AGE" env-default:"5m"`
+		OAuthStateTTL time.Duration `env:"SHOPIFY_OAUTH_STATE_TTL" env-default:"10m"`
+		// ApiVersion is the version of Admin API all requests are made to, it has to match api_version in shopify.app.toml.
+		ApiVersion string `env:"SHOPIFY_API_VERSION" env-default:"2025-10"`
+		// BulkOperationPollInterval is how often status of a bulk operation is checked while waiting for it to finish,
+		// unless bulk_operations/finish webhook is received first.
+		BulkOperationPollInterval time.Duration `env:"SHOPIFY_BULK_OPERATION_POLL_INTERVAL" env-default:"10s"`
+		// MaxRetries is the number of times Admin API requests failed because of connection or server errors are retried.
+		MaxRetries int `env:"SHOPIFY_MAX_RETRIES" env-default:"3"`
+		// ApiSecretPrevious is the secret replaced by ApiSecret, it's accepted until rotation is finished.
+		ApiSecretPrevious string `env:"SHOPIFY_API_SECRET_PREVIOUS" env-default:"" json:"-"`
+		// Apps are additional apps served by this backend,
+		// comma separated list of <handle>:<api key>:<secret>[:<previous secret>].
+		Apps string `env:"SHOPIFY_APPS" env-default:"" json:"-"`
+		// SessionTokenLeeway is the allowed clock skew when validating time claims of session tokens.
+		SessionTokenLeeway time.Duration `env:"SHOPIFY_SESSION_TOKEN_LEEWAY" env-default:"5s"`
+		// SessionTokenReplayProtection rejects session tokens which were already used by another request.
+		// App Bridge reuses a session token until it expires, so it's only suitable for clients fetching a new token per request.
+		SessionTokenReplayProtection bool `env:"SHOPIFY_SESSION_TOKEN_REPLAY_PROTECTION" env-default:"false"`
+		// ExpiringOfflineTokens enables expiring offline access tokens, which are refreshed with refresh tokens.
+		ExpiringOfflineTokens bool `env:"SHOPIFY_EXPIRING_OFFLINE_TOKENS" env-default:"false"`
+		// AccessTokenRefreshBefore is how long before expiry access tokens are refreshed.
+		AccessTokenRefreshBefore time.Duration `env:"SHOPIFY_ACCESS_TOKEN_REFRESH_BEFORE" env-default:"5m"`
+		// ProductsAccessMode is the access mode of requests managing products, either "online" or "offline".
+		// Online requests are made on behalf of the user and limited by their permissions.
+		ProductsAccessMode string `env:"SHOPIFY_PRODUCTS_ACCESS_MODE" env-default:"offline"`
 	}
 
 	HTTP struct {
@@ -33,6 +62,24 @@ type (
 		SendDetailsOnInternalError bool   `env:"HTTP_SEND_DETAILS_ON_INTERNAL_ERROR" env-default:"true"`
 	}
 
+	Webhooks struct {
+		DeduplicationTTL  time.Duration `env:"WEBHOOK_DEDUPLICATION_TTL" env-default:"72h"`
+		CleanupInterval   time.Duration `env:"WEBHOOK_CLEANUP_INTERVAL" env-default:"1h"`
+		Workers           int           `env:"WEBHOOK_WORKERS" env-default:"4"`
+		PollInterval      time.Duration `env:"WEBHOOK_POLL_INTERVAL" env-default:"1s"`
+		JobTimeout        time.Duration `env:"WEBHOOK_JOB_TIMEOUT" env-default:"1m"`
+		MaxAttempts       int           `env:"WEBHOOK_MAX_ATTEMPTS" env-default:"8"`
+		RetryBackoff      time.Duration `env:"WEBHOOK_RETRY_BACKOFF" env-default:"10s"`
+		RetryMaxBackoff   time.Duration `env:"WEBHOOK_RETRY_MAX_BACKOFF" env-default:"1h"`
+		ReconcileInterval time.Duration `env:"WEBHOOK_RECONCILE_INTERVAL" env-default:"24h"`
+	}
+
+	Encryption struct {
+		// Keys is comma separated list of <key id>:<base64 encoded 32 bytes key> pairs.
+		// The first key encrypts new values, the rest are used to decrypt values encrypted before rotation.
+		Keys string `env:"ENCRYPTION_KEYS" env-default:"" json:"-"`
+	}
+
 	DatabaseConfig struct {
 		Type     string `env:"DATABASE_TYPE" env-default:"postgres"`
 		Postgres Postgres
@@ -0,0 +1,419 @@
+package shopify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+// bulkOperationFields are selected for every bulk operation.
+const bulkOperationFields = `
+id
+type
+status
+errorCode
+objectCount
+url
+partialDataUrl
+createdAt
+completedAt`
+
+type bulkOperation struct {
+	ID             string     `json:"id"`
+	Type           string     `json:"type"`
+	Status         string     `json:"status"`
+	ErrorCode      *string    `json:"errorCode"`
+	ObjectCount    string     `json:"objectCount"`
+	URL            *string    `json:"url"`
+	PartialDataURL *string    `json:"partialDataUrl"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	CompletedAt    *time.Time `json:"completedAt"`
+}
+
+func (o bulkOperation) toEntity() *entity.BulkOperation {
+	// objectCount is UnsignedInt64, which is encoded as string
+	objectCount, _ := strconv.ParseInt(o.ObjectCount, 10, 64)
+	operation := &entity.BulkOperation{
+		ID:          o.ID,
+		Type:        o.Type,
+		Status:      entity.BulkOperationStatus(o.Status),
+		ObjectCount: objectCount,
+		CreatedAt:   o.CreatedAt,
+		CompletedAt: o.CompletedAt,
+	}
+	if o.ErrorCode != nil {
+		operation.ErrorCode = *o.ErrorCode
+	}
+	if o.URL != nil {
+		operation.URL = *o.URL
+	}
+	if o.PartialDataURL != nil {
+		operation.PartialDataURL = *o.PartialDataURL
+	}
+	return operation
+}
+
+// bulkOperationPayload is a payload of mutations starting bulk operation.
+type bulkOperationPayload struct {
+	BulkOperation *bulkOperation `json:"bulkOperation"`
+	userErrors
+}
+
+// operation returns the started bulk operation, or service.APIUserErrors if it's rejected.
+func (p bulkOperationPayload) operation() (*entity.BulkOperation, error) {
+	err := p.err()
+	if err != nil {
+		return nil, err
+	}
+	if p.BulkOperation == nil {
+		return nil, fmt.Errorf("response doesn't contain bulk operation")
+	}
+	return p.BulkOperation.toEntity(), nil
+}
+
+const bulkOperationRunQueryMutation = `
+mutation bulkOperationRunQuery($query: String!) {
+  bulkOperationRunQuery(query: $query) {
+    bulkOperation {` + bulkOperationFields + `
+    }
+    userErrors {
+      field
+      message
+      code
+    }
+  }
+}`
+
+type bulkOperationRunQueryData struct {
+	BulkOperationRunQuery bulkOperationPayload `json:"bulkOperationRunQuery"`
+}
+
+func (s *shopifyAPI) RunBulkQuery(ctx context.Context, query string) (*entity.BulkOperation, error) {
+	logger := s.logger.
+		Named("RunBulkQuery").
+		WithContext(ctx)
+
+	res, err := doGraphQL[bulkOperationRunQueryData](ctx, s, bulkOperationRunQueryMutation, map[string]any{
+		"query": query,
+	})
+	if err != nil {
+		logger.Error("failed to run bulk query", "err", err)
+		return nil, fmt.Errorf("failed to run bulk query: %w", err)
+	}
+	operation, err := res.Data.BulkOperationRunQuery.operation()
+	if err != nil {
+		logger.Info("bulk query is rejected", "err", err)
+		return nil, fmt.Errorf("failed to run bulk query: %w", err)
+	}
+
+	logger.Info("started bulk query", "operationID", operation.ID)
+	return operation, nil
+}
+
+const stagedUploadsCreateMutation = `
+mutation stagedUploadsCreate($input: [StagedUploadInput!]!) {
+  stagedUploadsCreate(input: $input) {
+    stagedTargets {
+      url
+      parameters {
+        name
+        value
+      }
+    }
+    userErrors {
+      field
+      message
+    }
+  }
+}`
+
+type stagedUploadInput struct {
+	Resource   string `json:"resource"`
+	Filename   string `json:"filename"`
+	MimeType   string `json:"mimeType"`
+	HTTPMethod string `json:"httpMethod"`
+}
+
+type stagedUploadTarget struct {
+	URL        string `json:"url"`
+	Parameters []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"parameters"`
+}
+
+type stagedUploadsCreateData struct {
+	StagedUploadsCreate struct {
+		StagedTargets []stagedUploadTarget `json:"stagedTargets"`
+		userErrors
+	} `json:"stagedUploadsCreate"`
+}
+
+const bulkOperationRunMutationMutation = `
+mutation bulkOperationRunMutation($mutation: String!, $stagedUploadPath: String!) {
+  bulkOperationRunMutation(mutation: $mutation, stagedUploadPath: $stagedUploadPath) {
+    bulkOperation {` + bulkOperationFields + `
+    }
+    userErrors {
+      field
+      message
+      code
+    }
+  }
+}`
+
+type bulkOperationRunMutationData struct {
+	BulkOperationRunMutation bulkOperationPayload `json:"bulkOperationRunMutation"`
+}
+
+func (s *shopifyAPI) RunBulkMutation(ctx context.Context, mutation string, variables []map[string]any) (*entity.BulkOperation, error) {
+	logger := s.logger.
+		Named("RunBulkMutation").
+		WithContext(ctx).
+		With("variables", len(variables))
+
+	// Variables of every run of the mutation are a line of JSONL file
+	var file bytes.Buffer
+	encoder := json.NewEncoder(&file)
+	for _, v := range variables {
+		err := encoder.Encode(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode variables: %w", err)
+		}
+	}
+
+	stagedUploadPath, err := s.stagedUpload(ctx, "bulk_op_vars.jsonl", &file)
+	if err != nil {
+		logger.Error("failed to upload variables", "err", err)
+		return nil, fmt.Errorf("failed to upload variables: %w", err)
+	}
+
+	res, err := doGraphQL[bulkOperationRunMutationData](ctx, s, bulkOperationRunMutationMutation, map[string]any{
+		"mutation":         mutation,
+		"stagedUploadPath": stagedUploadPath,
+	})
+	if err != nil {
+		logger.Error("failed to run bulk mutation", "err", err)
+		return nil, fmt.Errorf("failed to run bulk mutation: %w", err)
+	}
+	operation, err := res.Data.BulkOperationRunMutation.operation()
+	if err != nil {
+		logger.Info("bulk mutation is rejected", "err", err)
+		return nil, fmt.Errorf("failed to run bulk mutation: %w", err)
+	}
+
+	logger.Info("started bulk mutation", "operationID", operation.ID)
+	return operation, nil
+}
+
+// stagedUpload uploads JSONL file of bulk mutation variables and returns its path to run the mutation with.
+// File is uploaded to the staged upload target with the form parameters Shopify returns for it.
+func (s *shopifyAPI) stagedUpload(ctx context.Context, filename string, file io.Reader) (string, error) {
+	res, err := doGraphQL[stagedUploadsCreateData](ctx, s, stagedUploadsCreateMutation, map[string]any{
+		"input": []stagedUploadInput{{
+			Resource:   "BULK_MUTATION_VARIABLES",
+			Filename:   filename,
+			MimeType:   "text/jsonl",
+			HTTPMethod: http.MethodPost,
+		}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create staged upload: %w", err)
+	}
+	payload := res.Data.StagedUploadsCreate
+	err = payload.err()
+	if err != nil {
+		return "", fmt.Errorf("failed to create staged upload: %w", err)
+	}
+	if len(payload.StagedTargets) == 0 {
+		return "", fmt.Errorf("failed to create staged upload: response doesn't contain staged target")
+	}
+	target := payload.StagedTargets[0]
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	var stagedUploadPath string
+	for _, param := range target.Parameters {
+		if param.Name == "key" {
+			stagedUploadPath = param.Value
+		}
+		err = form.WriteField(param.Name, param.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to write form field: %w", err)
+		}
+	}
+	if stagedUploadPath == "" {
+		return "", fmt.Errorf("staged target doesn't contain key parameter")
+	}
+	// File has to be the last field of the form
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %w", err)
+	}
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to write form file: %w", err)
+	}
+	err = form.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to close form: %w", err)
+	}
+
+	// Staged target is not the store, so the file is uploaded without access token
+	uploadRes, err := s.newClient(http.DefaultTransport).R().
+		SetContext(ctx).
+		SetHeader("Content-Type", form.FormDataContentType()).
+		SetBody(body.Bytes()).
+		Post(target.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to send file: %w", err)
+	}
+	if uploadRes.IsError() {
+		return "", fmt.Errorf("failed to upload file: http status %d, body %s", uploadRes.StatusCode(), uploadRes.String())
+	}
+
+	return stagedUploadPath, nil
+}
+
+const bulkOperationQuery = `
+query bulkOperation($id: ID!) {
+  node(id: $id) {
+    ... on BulkOperation {` + bulkOperationFields + `
+    }
+  }
+}`
+
+type bulkOperationData struct {
+	Node *bulkOperation `json:"node"`
+}
+
+func (s *shopifyAPI) GetBulkOperation(ctx context.Context, operationID string) (*entity.BulkOperation, error) {
+	logger := s.logger.
+		Named("GetBulkOperation").
+		WithContext(ctx).
+		With("operationID", operationID)
+
+	res, err := doGraphQL[bulkOperationData](ctx, s, bulkOperationQuery, map[string]any{
+		"id": operationID,
+	})
+	if err != nil {
+		logger.Error("failed to get bulk operation", "err", err)
+		return nil, fmt.Errorf("failed to get bulk operation: %w", err)
+	}
+	if res.Data.Node == nil || res.Data.Node.ID == "" {
+		logger.Info("bulk operation is not found")
+		return nil, nil
+	}
+
+	return res.Data.Node.toEntity(), nil
+}
+
+// bulkObjectIDs are fields of a results line identifying the object and its parent.
+type bulkObjectIDs struct {
+	ID       string `json:"id"`
+	ParentID string `json:"__parentId"`
+}
+
+func (s *shopifyAPI) BulkOperationResults(ctx context.Context, resultsURL string) iter.Seq2[*entity.BulkObject, error] {
+	logger := s.logger.
+		Named("BulkOperationResults").
+		WithContext(ctx)
+
+	return func(yield func(*entity.BulkObject, error) bool) {
+		fail := func(err error) {
+			logger.Error("failed to read bulk operation results", "err", err)
+			yield(nil, fmt.Errorf("failed to read bulk operation results: %w", err))
+		}
+
+		// Results are stored outside of the store, so they are downloaded without access token
+		res, err := s.newClient(http.DefaultTransport).R().
+			SetContext(ctx).
+			SetDoNotParseResponse(true).
+			Get(resultsURL)
+		if err != nil {
+			fail(fmt.Errorf("failed to send request: %w", err))
+			return
+		}
+		body := res.RawBody()
+		defer body.Close()
+		if res.IsError() {
+			fail(fmt.Errorf("request failed: http status %d", res.StatusCode()))
+			return
+		}
+
+		// Nested objects follow their parent, so an object is complete once the next top-level object is read.
+		// Objects read before their parent are held until it's read, they are attached to it then.
+		// Only the current top-level object with its nested objects and such orphans are held in memory.
+		var current *entity.BulkObject
+		objects := make(map[string]*entity.BulkObject)
+		orphans := make(map[string][]*entity.BulkObject)
+		decoder := json.NewDecoder(body)
+		for line := 1; ; line++ {
+			var data json.RawMessage
+			err = decoder.Decode(&data)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				fail(fmt.Errorf("failed to decode line %d: %w", line, err))
+				return
+			}
+
+			var ids bulkObjectIDs
+			err = json.Unmarshal(data, &ids)
+			if err != nil {
+				fail(fmt.Errorf("failed to decode line %d: %w", line, err))
+				return
+			}
+
+			object := &entity.BulkObject{ID: ids.ID, Data: data}
+			if object.ID != "" {
+				object.Children = orphans[object.ID]
+				delete(orphans, object.ID)
+				objects[object.ID] = object
+			}
+
+			switch {
+			case ids.ParentID == "":
+				if current != nil {
+					if !yield(current, nil) {
+						return
+					}
+					forgetBulkObject(objects, current)
+				}
+				current = object
+			case objects[ids.ParentID] != nil:
+				parent := objects[ids.ParentID]
+				parent.Children = append(parent.Children, object)
+			default:
+				orphans[ids.ParentID] = append(orphans[ids.ParentID], object)
+			}
+		}
+
+		if current != nil && !yield(current, nil) {
+			return
+		}
+		for parentID := range orphans {
+			fail(fmt.Errorf("parent %s of nested objects is not found", parentID))
+			return
+		}
+	}
+}
+
+// forgetBulkObject removes object and its nested objects from objects by their IDs.
+func forgetBulkObject(objects map[string]*entity.BulkObject, object *entity.BulkObject) {
+	delete(objects, object.ID)
+	for _, child := range object.Children {
+		forgetBulkObject(objects, child)
+	}
+}
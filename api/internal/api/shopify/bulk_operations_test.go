@@ -0,0 +1,176 @@
+package shopify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+)
+
+// describeBulkObject returns object with its nested objects as a string, e.g. Product/1[ProductVariant/1],
+// objects without ID are described by their data.
+func describeBulkObject(object *entity.BulkObject) string {
+	description := strings.TrimPrefix(object.ID, "gid://shopify/")
+	if object.ID == "" {
+		description = string(object.Data)
+	}
+	if len(object.Children) == 0 {
+		return description
+	}
+
+	children := make([]string, 0, len(object.Children))
+	for _, child := range object.Children {
+		children = append(children, describeBulkObject(child))
+	}
+	return description + "[" + strings.Join(children, ",") + "]"
+}
+
+func TestBulkOperationResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "empty results",
+		},
+		{
+			name: "top-level objects",
+			lines: []string{
+				`{"id":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/Product/2"}`,
+			},
+			want: []string{"Product/1", "Product/2"},
+		},
+		{
+			name: "nested objects",
+			lines: []string{
+				`{"id":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/ProductVariant/1","__parentId":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/ProductVariant/2","__parentId":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/Product/2"}`,
+				`{"id":"gid://shopify/ProductVariant/3","__parentId":"gid://shopify/Product/2"}`,
+			},
+			want: []string{
+				"Product/1[ProductVariant/1,ProductVariant/2]",
+				"Product/2[ProductVariant/3]",
+			},
+		},
+		{
+			name: "grandchildren",
+			lines: []string{
+				`{"id":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/ProductVariant/1","__parentId":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/InventoryLevel/1","__parentId":"gid://shopify/ProductVariant/1"}`,
+				`{"id":"gid://shopify/ProductVariant/2","__parentId":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/InventoryLevel/2","__parentId":"gid://shopify/ProductVariant/1"}`,
+				`{"id":"gid://shopify/InventoryLevel/3","__parentId":"gid://shopify/ProductVariant/2"}`,
+			},
+			want: []string{
+				"Product/1[ProductVariant/1[InventoryLevel/1,InventoryLevel/2],ProductVariant/2[InventoryLevel/3]]",
+			},
+		},
+		{
+			name: "nested object before its parent",
+			lines: []string{
+				`{"id":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/InventoryLevel/1","__parentId":"gid://shopify/ProductVariant/1"}`,
+				`{"id":"gid://shopify/ProductVariant/1","__parentId":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/ProductVariant/2","__parentId":"gid://shopify/Product/2"}`,
+				`{"id":"gid://shopify/InventoryLevel/2","__parentId":"gid://shopify/ProductVariant/2"}`,
+				`{"id":"gid://shopify/Product/2"}`,
+			},
+			want: []string{
+				"Product/1[ProductVariant/1[InventoryLevel/1]]",
+				"Product/2[ProductVariant/2[InventoryLevel/2]]",
+			},
+		},
+		{
+			name: "mutation results without id",
+			lines: []string{
+				`{"data":{"productSet":{"userErrors":[]}},"__lineNumber":0}`,
+				`{"data":{"productSet":{"userErrors":[]}},"__lineNumber":1}`,
+			},
+			want: []string{
+				`{"data":{"productSet":{"userErrors":[]}},"__lineNumber":0}`,
+				`{"data":{"productSet":{"userErrors":[]}},"__lineNumber":1}`,
+			},
+		},
+		{
+			name: "parent is not found",
+			lines: []string{
+				`{"id":"gid://shopify/Product/1"}`,
+				`{"id":"gid://shopify/ProductVariant/1","__parentId":"gid://shopify/Product/2"}`,
+			},
+			want:    []string{"Product/1"},
+			wantErr: true,
+		},
+		{
+			name: "malformed line",
+			lines: []string{
+				`{"id":"gid://shopify/Product/1"}`,
+				`{"id":`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/jsonl")
+				for _, line := range tt.lines {
+					_, _ = w.Write([]byte(line + "\n"))
+				}
+			}))
+			defer server.Close()
+
+			s := &shopifyAPI{logger: logging.NewZap("error")}
+			var got []string
+			var err error
+			for object, objectErr := range s.BulkOperationResults(context.Background(), server.URL) {
+				if objectErr != nil {
+					err = objectErr
+					break
+				}
+				got = append(got, describeBulkObject(object))
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("objects = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkOperationResultsFailedDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	s := &shopifyAPI{logger: logging.NewZap("error")}
+	var err error
+	for object, objectErr := range s.BulkOperationResults(context.Background(), server.URL) {
+		if objectErr == nil {
+			t.Fatalf("expected error, got object %s", describeBulkObject(object))
+		}
+		err = objectErr
+	}
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
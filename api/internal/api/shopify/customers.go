@@ -0,0 +1,85 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+const customersQuery = `
+query customers($first: Int!, $after: String) {
+  customers(first: $first, after: $after) {
+    nodes {
+      id
+      firstName
+      lastName
+      defaultEmailAddress {
+        emailAddress
+      }
+      defaultPhoneNumber {
+        phoneNumber
+      }
+      state
+      createdAt
+      updatedAt
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}`
+
+type customer struct {
+	ID                  string `json:"id"`
+	FirstName           string `json:"firstName"`
+	LastName            string `json:"lastName"`
+	DefaultEmailAddress *struct {
+		EmailAddress string `json:"emailAddress"`
+	} `json:"defaultEmailAddress"`
+	DefaultPhoneNumber *struct {
+		PhoneNumber string `json:"phoneNumber"`
+	} `json:"defaultPhoneNumber"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (c customer) toEntity() entity.Customer {
+	customer := entity.Customer{
+		ID:        c.ID,
+		FirstName: c.FirstName,
+		LastName:  c.LastName,
+		State:     c.State,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	if c.DefaultEmailAddress != nil {
+		customer.Email = c.DefaultEmailAddress.EmailAddress
+	}
+	if c.DefaultPhoneNumber != nil {
+		customer.Phone = c.DefaultPhoneNumber.PhoneNumber
+	}
+	return customer
+}
+
+type customersData struct {
+	Customers connection[customer] `json:"customers"`
+}
+
+func (s *shopifyAPI) ListCustomers(ctx context.Context) iter.Seq2[entity.Customer, error] {
+	logger := s.logger.
+		Named("ListCustomers").
+		WithContext(ctx)
+
+	customers := paginateGraphQL(ctx, s, customersQuery, nil, 0, func(data *customersData) *connection[customer] {
+		return &data.Customers
+	})
+	return convertItems(customers, customer.toEntity, func(err error) error {
+		logger.Error("failed to list customers", "err", err)
+		return fmt.Errorf("failed to list customers: %w", err)
+	})
+}
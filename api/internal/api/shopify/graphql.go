@@ -0,0 +1,188 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/antflydb/shopify-app-template-go/internal/service"
+)
+
+// graphQLRequest is a query or mutation with its variables.
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// graphQLResponse is a response of Admin GraphQL API with data decoded into T.
+type graphQLResponse[T any] struct {
+	Data       *T            `json:"data"`
+	Errors     graphQLErrors `json:"errors"`
+	Extensions struct {
+		Cost *queryCost `json:"cost"`
+	} `json:"extensions"`
+}
+
+// graphQLResult is data returned by a query or mutation along with its cost.
+type graphQLResult[T any] struct {
+	Data T
+	// Cost is nil if the response doesn't contain cost of the query.
+	Cost *queryCost
+}
+
+// queryCost is the cost of a query in points of the app's rate limit.
+// https://shopify.dev/docs/api/usage/rate-limits#graphql-admin-api-rate-limits
+type queryCost struct {
+	RequestedQueryCost int `json:"requestedQueryCost"`
+	// ActualQueryCost is nil if the query wasn't executed, e.g. because it was throttled.
+	ActualQueryCost *int           `json:"actualQueryCost"`
+	ThrottleStatus  throttleStatus `json:"throttleStatus"`
+}
+
+// throttleStatus is the state of the app's rate limit bucket in the store.
+type throttleStatus struct {
+	MaximumAvailable   float64 `json:"maximumAvailable"`
+	CurrentlyAvailable float64 `json:"currentlyAvailable"`
+	RestoreRate        float64 `json:"restoreRate"`
+}
+
+// graphQLError is a top-level error of a response, e.g. syntax error or throttled query.
+type graphQLError struct {
+	Message    string `json:"message"`
+	Path       []any  `json:"path,omitempty"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+// graphQLErrors is returned when query or mutation can't be executed.
+type graphQLErrors []graphQLError
+
+// throttled reports whether query wasn't executed because there were not enough points available.
+func (e graphQLErrors) throttled() bool {
+	for _, graphQLErr := range e {
+		if graphQLErr.Extensions.Code == "THROTTLED" {
+			return true
+		}
+	}
+	return false
+}
+
+func (e graphQLErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, graphQLErr := range e {
+		if graphQLErr.Extensions.Code != "" {
+			messages = append(messages, graphQLErr.Extensions.Code+": "+graphQLErr.Message)
+			continue
+		}
+		messages = append(messages, graphQLErr.Message)
+	}
+	return "graphql errors: " + strings.Join(messages, "; ")
+}
+
+// userError is an error of mutation input, every mutation payload contains list of them.
+type userError struct {
+	Field   []string `json:"field"`
+	Message string   `json:"message"`
+	Code    string   `json:"code,omitempty"`
+}
+
+// userErrors is embedded into mutation payloads.
+type userErrors struct {
+	UserErrors []userError `json:"userErrors"`
+}
+
+// err returns service.APIUserErrors if mutation input was rejected.
+func (u userErrors) err() error {
+	if len(u.UserErrors) == 0 {
+		return nil
+	}
+
+	apiErrs := make(service.APIUserErrors, 0, len(u.UserErrors))
+	for _, userErr := range u.UserErrors {
+		apiErrs = append(apiErrs, service.APIUserError{
+			Field:   userErr.Field,
+			Message: userErr.Message,
+			Code:    userErr.Code,
+		})
+	}
+	return apiErrs
+}
+
+// doGraphQL executes query or mutation with variables and decodes its data into T.
+// Top-level errors of the response are returned as graphQLErrors,
+// mutation payloads have to be checked for user errors by the caller.
+// Query is sent once enough points of the store's rate limit are available for its estimated cost,
+// and sent again if it's throttled anyway.
+func doGraphQL[T any](ctx context.Context, s *shopifyAPI, query string, variables map[string]any) (*graphQLResult[T], error) {
+	for attempt := 1; ; attempt++ {
+		result, err := sendGraphQL[T](ctx, s, query, variables)
+		var graphQLErrs graphQLErrors
+		if attempt < rateLimitMaxAttempts && errors.As(err, &graphQLErrs) && graphQLErrs.throttled() {
+			s.logger.Named("doGraphQL").WithContext(ctx).Info("graphql request is throttled, retrying", "attempt", attempt)
+			continue
+		}
+		return result, err
+	}
+}
+
+// sendGraphQL sends query or mutation once.
+func sendGraphQL[T any](ctx context.Context, s *shopifyAPI, query string, variables map[string]any) (*graphQLResult[T], error) {
+	logger := s.logger.
+		Named("sendGraphQL").
+		WithContext(ctx)
+
+	if s.limiter != nil {
+		err := wait(ctx, s.limiter.reserveGraphQL(s.rateLimiters.estimateCost(query)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for rate limit: %w", err)
+		}
+	}
+
+	var responseBody graphQLResponse[T]
+	res, err := s.client.R().
+		SetContext(ctx).
+		SetBody(graphQLRequest{
+			Query:     query,
+			Variables: variables,
+		}).
+		SetResult(&responseBody).
+		Post(s.graphQLPath())
+	if err != nil {
+		logger.Error("failed to send graphql request", "err", err)
+		return nil, fmt.Errorf("failed to send graphql request: %w", err)
+	}
+	if res.StatusCode() != http.StatusOK {
+		logger.Error("graphql request failed", "status", res.StatusCode(), "resBody", res.String())
+		return nil, fmt.Errorf("graphql request failed: http status %d, body %s", res.StatusCode(), res.String())
+	}
+
+	cost := responseBody.Extensions.Cost
+	if cost != nil {
+		s.rateLimiters.queryCosts.Store(query, float64(cost.RequestedQueryCost))
+		if s.limiter != nil {
+			s.limiter.updateGraphQL(cost.ThrottleStatus)
+		}
+		logger.Debug("graphql query cost",
+			"requested", cost.RequestedQueryCost,
+			"actual", cost.ActualQueryCost,
+			"available", cost.ThrottleStatus.CurrentlyAvailable,
+		)
+	}
+
+	if len(responseBody.Errors) > 0 {
+		logger.Info("graphql request returned errors", "errors", responseBody.Errors)
+		return nil, responseBody.Errors
+	}
+	if responseBody.Data == nil {
+		logger.Error("graphql response doesn't contain data")
+		return nil, fmt.Errorf("graphql response doesn't contain data")
+	}
+
+	return &graphQLResult[T]{
+		Data: *responseBody.Data,
+		Cost: cost,
+	}, nil
+}
@@ -1,12 +1,16 @@
 package shopify
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/internal/service"
-	"github.com/google/uuid"
+	"github.com/antflydb/shopify-app-template-go/pkg/errs"
 )
 
 func (s *shopifyAPI) HandleInstall(opts service.HandleInstallOptions) (service.APIHandleInstallOutput, error) {
@@ -14,86 +18,153 @@ func (s *shopifyAPI) HandleInstall(opts service.HandleInstallOptions) (service.A
 		Named("HandleInstall").
 		With("opts", opts)
 
-	storeNonce := s.generateNonce()
+	shop, err := entity.ParseShopDomain(opts.StoreName)
+	if err != nil {
+		logger.Info("invalid shop domain")
+		return service.APIHandleInstallOutput{}, err
+	}
+
+	storeNonce, err := s.generateNonce()
+	if err != nil {
+		logger.Error("failed to generate nonce", "err", err)
+		return service.APIHandleInstallOutput{}, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
 	// Build redirection URL
 	values := url.Values{
-		"client_id":       {s.cfg.Shopify.ApiKey},
-		"scope":           {s.cfg.Shopify.Scopes},
-		"redirect_uri":    {opts.RedirectURL},
-		"state":           {storeNonce},
-		"grant_options[]": {"offline"}, // https://shopify.dev/concepts/about-apis/authentication#api-access-modes
+		"client_id":    {s.app.APIKey},
+		"scope":        {s.cfg.Shopify.Scopes},
+		"redirect_uri": {opts.RedirectURL},
+		"state":        {storeNonce},
+	}
+	// Offline access mode is the default one
+	// https://shopify.dev/docs/apps/build/authentication-authorization/access-tokens/about-access-modes
+	if opts.AccessMode == entity.AccessModeOnline {
+		values.Set("grant_options[]", "per-user")
 	}
 
 	logger = logger.With("values", values)
 	logger.Debug("built values")
 	return service.APIHandleInstallOutput{
 		Nonce:       storeNonce,
-		RedirectURL: fmt.Sprintf("https://%s/admin/oauth/authorize?%s", opts.StoreName, values.Encode()),
+		RedirectURL: shop.URL("/admin/oauth/authorize?" + values.Encode()),
 	}, nil
 }
 
-func (s *shopifyAPI) HandleRedirect(opts service.APIHandleRedirectOptions) (string, error) {
+func (s *shopifyAPI) VerifyRedirect(opts service.APIHandleRedirectOptions) error {
 	logger := s.logger.
-		Named("HandleRedirect").
+		Named("VerifyRedirect").
 		With("opts", opts)
 
-	// Verify redirected URL
+	shop, err := entity.ParseShopDomain(opts.StoreName)
+	if err != nil {
+		logger.Info("invalid shop domain")
+		return err
+	}
+
 	parsedURL, err := url.Parse(opts.RedirectedURL)
 	if err != nil {
 		logger.Info("failed to parse redirected url", "err", err)
-		return "", service.ErrHandleRedirectInvalidRedirectedURL
+		return service.ErrHandleRedirectInvalidRedirectedURL
+	}
+	query := parsedURL.Query()
+	err = s.verifyQuery(query)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info("failed to verify redirected url signature", "err", err)
+			return service.ErrHandleRedirectInvalidRedirectedURL
+		}
+		logger.Error("failed to verify redirected url signature", "err", err)
+		return fmt.Errorf("failed to verify redirected url signature: %w", err)
+	}
+	// Callback has to come for the store which started authorization
+	callbackShop, err := entity.ParseShopDomain(query.Get("shop"))
+	if err != nil || callbackShop != shop {
+		logger.Info("shop doesn't match store", "shop", query.Get("shop"))
+		return service.ErrHandleRedirectInvalidRedirectedURL
 	}
 	if !s.verifyNonce(opts.Nonce, parsedURL) {
 		logger.Info("nonce is incorrect")
-		return "", service.ErrHandleRedirectInvalidRedirectedURL
+		return service.ErrHandleRedirectInvalidRedirectedURL
 	}
+
 	logger.Debug("verified redirected url")
+	return nil
+}
 
-	// Getting access token
+func (s *shopifyAPI) HandleRedirect(opts service.APIHandleRedirectOptions) (*service.APIHandleRedirectOutput, error) {
+	logger := s.logger.
+		Named("HandleRedirect").
+		With("opts", opts)
+
+	err := s.VerifyRedirect(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Shop and query are valid once redirected URL is verified
+	shop, _ := entity.ParseShopDomain(opts.StoreName)
+	parsedURL, _ := url.Parse(opts.RedirectedURL)
 	query := parsedURL.Query()
-	var credentials map[string]string
+
+	// Getting access token
+	params := map[string]string{
+		"client_id":     s.app.APIKey,
+		"client_secret": s.app.Secret(),
+		"code":          query.Get("code"),
+	}
+	if s.cfg.Shopify.ExpiringOfflineTokens {
+		params["expiring"] = "1"
+	}
+
+	var credentials accessTokenResponse
 	res, err := s.client.R().
-		SetQueryParams(map[string]string{
-			"client_id":     s.cfg.Shopify.ApiKey,
-			"client_secret": s.cfg.Shopify.ApiSecret,
-			"code":          query.Get("code"),
-		}).
+		SetQueryParams(params).
 		SetResult(&credentials).
-		Post(fmt.Sprintf("https://%s/admin/oauth/access_token", opts.StoreName))
+		Post(shop.URL("/admin/oauth/access_token"))
 	if err != nil {
 		logger.Error("failed to get shopifyAPI access token", "err", err)
-		return "", fmt.Errorf("failed to get shopifyAPI access token: %w", err)
+		return nil, fmt.Errorf("failed to get shopifyAPI access token: %w", err)
 	}
 	if res.StatusCode() != http.StatusOK {
 		logger.Error("failed to get shopifyAPI access token", "resBody", res.String())
-		return "", fmt.Errorf("failed to get shopifyAPI access token: http status %d, body %s", res.StatusCode(), res.String())
+		return nil, fmt.Errorf("failed to get shopifyAPI access token: http status %d, body %s", res.StatusCode(), res.String())
 	}
-	if credentials["scope"] != s.cfg.Shopify.Scopes {
-		logger.Info("scopes are different", "resBody", res.String())
-		return "", service.ErrHandleRedirectInvalidScopes
+	// Scopes are compared as sets, as granted scopes can be reordered or include implied ones
+	granted := entity.ParseAccessScopes(credentials.Scope)
+	required := entity.ParseAccessScopes(s.cfg.Shopify.Scopes)
+	if !granted.Covers(required) {
+		logger.Info("not all requested scopes are granted", "granted", granted.String(), "missing", granted.Missing(required))
+		return nil, service.ErrHandleRedirectInvalidScopes
 	}
-	logger = logger.With("resBody", res.String())
+	logger = logger.With("scopes", granted.String(), "expiresAt", credentials.expiresAt())
 	logger.Info("got credentials")
 
-	return credentials["access_token"], nil
+	return &service.APIHandleRedirectOutput{
+		AccessToken:           credentials.AccessToken,
+		Scopes:                granted.String(),
+		ExpiresAt:             credentials.expiresAt(),
+		RefreshToken:          credentials.RefreshToken,
+		RefreshTokenExpiresAt: credentials.refreshTokenExpiresAt(),
+	}, nil
 }
 
 // verifyNonce verifies nonce from given url with the actual one.
+// Both nonces are required to be present.
 func (s *shopifyAPI) verifyNonce(actualNonce string, url *url.URL) bool {
 	q := url.Query()
 	nonce := q.Get("state")
-	if nonce == "" {
-		return true
+	if nonce == "" || actualNonce == "" {
+		return false
 	}
-	return nonce == actualNonce
+	return subtle.ConstantTimeCompare([]byte(nonce), []byte(actualNonce)) == 1
 }
 
 // generateNonce is used to generate random nonce.
-func (s *shopifyAPI) generateNonce() string {
-	nonce, err := uuid.NewUUID()
+func (s *shopifyAPI) generateNonce() (string, error) {
+	nonce := make([]byte, 32)
+	_, err := rand.Read(nonce)
 	if err != nil {
-		// If nonce couldn't be generated, use default string
-		return "jg6yf3JAdB5hvFaG1o"
+		return "", err
 	}
-	return nonce.String()
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
 }
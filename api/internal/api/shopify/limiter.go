@@ -0,0 +1,279 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerShopifyShopAPICallLimit = "X-Shopify-Shop-Api-Call-Limit"
+	headerRetryAfter              = "Retry-After"
+
+	// rateLimitMaxAttempts is the number of attempts of a request rejected because of rate limit.
+	rateLimitMaxAttempts = 5
+	// defaultRetryAfter is used when throttled response doesn't say how long to wait.
+	defaultRetryAfter = time.Second
+)
+
+// rateLimiters holds rate limiter per app and store, shared by all shopifyAPI instances,
+// as Shopify limits requests of an app per store regardless of the access token used.
+type rateLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+
+	// queryCosts holds requested cost of every GraphQL query sent, it's used to estimate cost of the next request
+	queryCosts sync.Map
+}
+
+func newRateLimiters() *rateLimiters {
+	return &rateLimiters{limiters: make(map[string]*rateLimiter)}
+}
+
+// get returns rate limiter of the app's requests to the store.
+func (r *rateLimiters) get(app, storeName string) *rateLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := app + "/" + storeName
+	limiter, ok := r.limiters[key]
+	if !ok {
+		limiter = &rateLimiter{now: time.Now}
+		r.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// estimateCost returns expected cost of GraphQL query, which is its cost last time it was sent.
+func (r *rateLimiters) estimateCost(query string) float64 {
+	cost, ok := r.queryCosts.Load(query)
+	if !ok {
+		return 1
+	}
+	return cost.(float64)
+}
+
+// rateLimiter paces requests of an app to a store, so they are not rejected because of rate limits.
+// REST requests are limited by a leaky bucket of requests reported in X-Shopify-Shop-Api-Call-Limit header,
+// GraphQL requests by a bucket of query cost points reported in extensions.cost.throttleStatus.
+// Capacity is reserved before a request is sent and corrected with the state reported in its response.
+// https://shopify.dev/docs/api/usage/rate-limits
+type rateLimiter struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	// blockedUntil is when requests can be sent again after a request was rejected with Retry-After
+	blockedUntil time.Time
+
+	// restUsed is the number of requests in REST bucket at restUpdatedAt, restMax is zero until it's reported
+	restUsed      float64
+	restMax       float64
+	restLeakRate  float64
+	restUpdatedAt time.Time
+
+	// graphQLAvailable is the number of points available at graphQLUpdatedAt, graphQLMax is zero until it's reported
+	graphQLAvailable   float64
+	graphQLMax         float64
+	graphQLRestoreRate float64
+	graphQLUpdatedAt   time.Time
+}
+
+// reserveREST reserves place for a REST request in the bucket and returns how long to wait before sending it.
+func (l *rateLimiter) reserveREST() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	delay := l.blockedUntil.Sub(now)
+
+	if l.restMax > 0 {
+		l.restUsed = max(l.restUsed-now.Sub(l.restUpdatedAt).Seconds()*l.restLeakRate, 0)
+		l.restUpdatedAt = now
+		if overflow := l.restUsed + 1 - l.restMax; overflow > 0 {
+			delay = max(delay, seconds(overflow/l.restLeakRate))
+		}
+		l.restUsed++
+	}
+
+	return max(delay, 0)
+}
+
+// reserveGraphQL reserves points for a GraphQL query of the given cost and returns how long to wait before sending it.
+func (l *rateLimiter) reserveGraphQL(cost float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	delay := l.blockedUntil.Sub(now)
+
+	if l.graphQLMax > 0 {
+		l.graphQLAvailable = min(l.graphQLAvailable+now.Sub(l.graphQLUpdatedAt).Seconds()*l.graphQLRestoreRate, l.graphQLMax)
+		l.graphQLUpdatedAt = now
+		// Query costing more than the bucket holds is sent once the bucket is full
+		if missing := min(cost, l.graphQLMax) - l.graphQLAvailable; missing > 0 {
+			delay = max(delay, seconds(missing/l.graphQLRestoreRate))
+		}
+		l.graphQLAvailable -= cost
+	}
+
+	return max(delay, 0)
+}
+
+// updateREST sets state of REST bucket from X-Shopify-Shop-Api-Call-Limit header, e.g. 32/40.
+func (l *rateLimiter) updateREST(header string) {
+	rawUsed, rawMax, ok := strings.Cut(header, "/")
+	if !ok {
+		return
+	}
+	used, err := strconv.ParseFloat(rawUsed, 64)
+	if err != nil {
+		return
+	}
+	limit, err := strconv.ParseFloat(rawMax, 64)
+	if err != nil || limit <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.restUsed = used
+	l.restMax = limit
+	// Bucket leaks 2 requests per second on standard plans with 40 requests buckets and proportionally more on bigger ones
+	l.restLeakRate = limit / 20
+	l.restUpdatedAt = l.now()
+}
+
+// updateGraphQL sets state of GraphQL bucket from throttle status of a response.
+func (l *rateLimiter) updateGraphQL(status throttleStatus) {
+	if status.MaximumAvailable <= 0 || status.RestoreRate <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.graphQLAvailable = status.CurrentlyAvailable
+	l.graphQLMax = status.MaximumAvailable
+	l.graphQLRestoreRate = status.RestoreRate
+	l.graphQLUpdatedAt = l.now()
+}
+
+// blockedFor returns how long requests have to wait after a request was rejected with Retry-After.
+func (l *rateLimiter) blockedFor() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return max(l.blockedUntil.Sub(l.now()), 0)
+}
+
+// block stops requests from being sent for the given duration.
+func (l *rateLimiter) block(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := l.now().Add(d); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// wait waits for the given duration unless ctx is done first.
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// retryAfter returns how long to wait according to Retry-After header in seconds.
+func retryAfter(header http.Header) time.Duration {
+	s, err := strconv.ParseFloat(header.Get(headerRetryAfter), 64)
+	if err != nil || s <= 0 {
+		return defaultRetryAfter
+	}
+	return seconds(s)
+}
+
+// rateLimitTransport paces requests to a store with its rate limiter.
+// Request rejected with 429 is sent again after the time given by Retry-After,
+// and other requests to the store wait for it as well.
+type rateLimitTransport struct {
+	limiter *rateLimiter
+	base    http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		// GraphQL requests have reserved points by their cost before they are sent
+		var delay time.Duration
+		if isGraphQLRequest(req) {
+			delay = t.limiter.blockedFor()
+		} else {
+			delay = t.limiter.reserveREST()
+		}
+		err := wait(req.Context(), delay)
+		if err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 1 && req.Body != nil {
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body, err = req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body again: %w", err)
+			}
+		}
+
+		res, err := t.roundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+		if header := res.Header.Get(headerShopifyShopAPICallLimit); header != "" {
+			t.limiter.updateREST(header)
+		}
+		if res.StatusCode != http.StatusTooManyRequests {
+			return res, nil
+		}
+
+		t.limiter.block(retryAfter(res.Header))
+		// Request can be sent again only if its body can be read again
+		if attempt >= rateLimitMaxAttempts || (req.Body != nil && req.GetBody == nil) {
+			return res, nil
+		}
+
+		// Body of the rejected response is not needed anymore
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+}
+
+func (t *rateLimitTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+func isGraphQLRequest(req *http.Request) bool {
+	return strings.HasSuffix(req.URL.Path, "/graphql.json")
+}
@@ -0,0 +1,52 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+// order is an order of REST Admin API, orders are listed through REST
+// as it returns them with totals and statuses without selecting nested objects.
+type order struct {
+	AdminGraphqlID    string    `json:"admin_graphql_api_id"`
+	Name              string    `json:"name"`
+	Email             string    `json:"email"`
+	Currency          string    `json:"currency"`
+	TotalPrice        string    `json:"total_price"`
+	FinancialStatus   string    `json:"financial_status"`
+	FulfillmentStatus string    `json:"fulfillment_status"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func (o order) toEntity() entity.Order {
+	return entity.Order{
+		ID:                o.AdminGraphqlID,
+		Name:              o.Name,
+		Email:             o.Email,
+		Currency:          o.Currency,
+		TotalPrice:        o.TotalPrice,
+		FinancialStatus:   o.FinancialStatus,
+		FulfillmentStatus: o.FulfillmentStatus,
+		CreatedAt:         o.CreatedAt,
+		UpdatedAt:         o.UpdatedAt,
+	}
+}
+
+func (s *shopifyAPI) ListOrders(ctx context.Context) iter.Seq2[entity.Order, error] {
+	logger := s.logger.
+		Named("ListOrders").
+		WithContext(ctx)
+
+	// Orders of any status are listed, only open ones are listed otherwise
+	orders := paginateREST[order](ctx, s, "orders", url.Values{"status": {"any"}})
+	return convertItems(orders, order.toEntity, func(err error) error {
+		logger.Error("failed to list orders", "err", err)
+		return fmt.Errorf("failed to list orders: %w", err)
+	})
+}
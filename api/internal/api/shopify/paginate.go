@@ -0,0 +1,158 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerLink = "Link"
+
+	// pageSize is the number of items requested per page, the maximum Admin API allows.
+	pageSize = 250
+)
+
+// pageInfo is the page info of a GraphQL connection.
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// connection is a page of a GraphQL connection selected with nodes and pageInfo.
+type connection[N any] struct {
+	Nodes    []N      `json:"nodes"`
+	PageInfo pageInfo `json:"pageInfo"`
+}
+
+// paginateGraphQL returns iterator over nodes of the connection returned by query.
+// Query has to accept $first and $after variables, and conn has to return the connection from its data.
+// Pages of first nodes are requested as they are iterated, first is capped at pageSize,
+// and pages of pageSize nodes are requested if it's zero. Iteration stops at the first error.
+func paginateGraphQL[T, N any](ctx context.Context, s *shopifyAPI, query string, variables map[string]any, first int, conn func(*T) *connection[N]) iter.Seq2[N, error] {
+	return func(yield func(N, error) bool) {
+		var zero N
+
+		pageVariables := make(map[string]any, len(variables)+2)
+		maps.Copy(pageVariables, variables)
+		pageVariables["first"] = pageSize
+		if first > 0 {
+			pageVariables["first"] = min(first, pageSize)
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			res, err := doGraphQL[T](ctx, s, query, pageVariables)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			page := conn(&res.Data)
+			for _, node := range page.Nodes {
+				if !yield(node, nil) {
+					return
+				}
+			}
+			if !page.PageInfo.HasNextPage {
+				return
+			}
+			pageVariables["after"] = page.PageInfo.EndCursor
+		}
+	}
+}
+
+// paginateREST returns iterator over items of REST resource, e.g. orders listed as {"orders": [...]}.
+// Next pages are requested with page_info of the Link header, as Shopify doesn't accept other filters along with it.
+// Pages are requested as they are iterated, iteration stops at the first error.
+func paginateREST[N any](ctx context.Context, s *shopifyAPI, resource string, params url.Values) iter.Seq2[N, error] {
+	return func(yield func(N, error) bool) {
+		var zero N
+
+		pageParams := url.Values{}
+		maps.Copy(pageParams, params)
+		pageParams.Set("limit", strconv.Itoa(pageSize))
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			var responseBody map[string][]N
+			res, err := s.client.R().
+				SetContext(ctx).
+				SetQueryParamsFromValues(pageParams).
+				SetResult(&responseBody).
+				Get(s.restPath(resource))
+			if err != nil {
+				yield(zero, fmt.Errorf("failed to send request: %w", err))
+				return
+			}
+			if res.StatusCode() != http.StatusOK {
+				yield(zero, fmt.Errorf("request failed: http status %d, body %s", res.StatusCode(), res.String()))
+				return
+			}
+
+			for _, item := range responseBody[resource] {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			nextPageInfo := nextPageInfo(res.Header().Get(headerLink))
+			if nextPageInfo == "" {
+				return
+			}
+			pageParams = url.Values{
+				"limit":     {strconv.Itoa(pageSize)},
+				"page_info": {nextPageInfo},
+			}
+		}
+	}
+}
+
+// convertItems returns iterator converting items of a paginator with convert.
+// Error of the paginator is passed to wrapErr, e.g. to log it and add context.
+func convertItems[N, E any](items iter.Seq2[N, error], convert func(N) E, wrapErr func(error) error) iter.Seq2[E, error] {
+	return func(yield func(E, error) bool) {
+		for item, err := range items {
+			if err != nil {
+				var zero E
+				yield(zero, wrapErr(err))
+				return
+			}
+			if !yield(convert(item), nil) {
+				return
+			}
+		}
+	}
+}
+
+// nextPageInfo returns page_info of the next page from Link header, or empty string on the last page, e.g.
+// <https://shop.myshopify.com/admin/api/2025-10/orders.json?limit=250&page_info=abc>; rel="next".
+// Only page_info is taken from the link, so requests are sent to the store they are made for.
+func nextPageInfo(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		rawURL, params, ok := strings.Cut(link, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(rawURL), "<>"))
+		if err != nil {
+			return ""
+		}
+		return u.Query().Get("page_info")
+	}
+	return ""
+}
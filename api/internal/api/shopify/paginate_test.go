@@ -0,0 +1,63 @@
+package shopify
+
+import "testing"
+
+func TestNextPageInfo(t *testing.T) {
+	const baseURL = "https://example.myshopify.com/admin/api/2025-10/orders.json"
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{
+			name:   "no link header",
+			header: "",
+			want:   "",
+		},
+		{
+			name:   "next page",
+			header: `<` + baseURL + `?limit=250&page_info=next-page>; rel="next"`,
+			want:   "next-page",
+		},
+		{
+			name:   "last page",
+			header: `<` + baseURL + `?limit=250&page_info=previous-page>; rel="previous"`,
+			want:   "",
+		},
+		{
+			name:   "previous page before next page",
+			header: `<` + baseURL + `?limit=250&page_info=previous-page>; rel="previous", <` + baseURL + `?limit=250&page_info=next-page>; rel="next"`,
+			want:   "next-page",
+		},
+		{
+			name:   "next page before previous page",
+			header: `<` + baseURL + `?limit=250&page_info=next-page>; rel="next", <` + baseURL + `?limit=250&page_info=previous-page>; rel="previous"`,
+			want:   "next-page",
+		},
+		{
+			name:   "escaped page info",
+			header: `<` + baseURL + `?page_info=a%2Bb%3D&limit=250>; rel="next"`,
+			want:   "a+b=",
+		},
+		{
+			name:   "link without rel",
+			header: `<` + baseURL + `?limit=250&page_info=next-page>`,
+			want:   "",
+		},
+		{
+			name:   "malformed url",
+			header: `<%zz>; rel="next"`,
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextPageInfo(tt.header)
+			if got != tt.want {
+				t.Errorf("nextPageInfo() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
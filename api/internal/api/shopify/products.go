@@ -2,83 +2,539 @@ package shopify
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math/rand"
-	"net/http"
+	"iter"
+	"slices"
+	"time"
 
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/internal/service"
 )
 
-type createProductRequestBody struct {
-	Product *product `json:"product"`
+// productFields are selected for every product.
+const productFields = `
+id
+title
+descriptionHtml
+handle
+vendor
+productType
+status
+tags
+createdAt
+updatedAt`
+
+// productDetailsFields are selected for a single product along with productFields.
+// Product can have more variants, only the first page of them is selected.
+const productDetailsFields = `
+options {
+  id
+  name
+  position
+  optionValues {
+    name
+  }
+}
+variants(first: 250) {
+  nodes {
+    id
+    title
+    sku
+    barcode
+    price
+    compareAtPrice
+    inventoryQuantity
+    selectedOptions {
+      name
+      value
+    }
+  }
+}
+media(first: 250) {
+  nodes {
+    ... on MediaImage {
+      id
+      alt
+      image {
+        url
+      }
+    }
+  }
 }
+metafields(first: 250) {
+  nodes {
+    id
+    namespace
+    key
+    type
+    value
+  }
+}`
 
 type product struct {
-	Title       string `json:"title"`
-	BodyHTML    string `json:"body_html"`
-	Vendor      string `json:"vendor"`
-	ProductType string `json:"product_type"`
-	Status      string `json:"status"`
+	ID              string    `json:"id"`
+	Title           string    `json:"title"`
+	DescriptionHTML string    `json:"descriptionHtml"`
+	Handle          string    `json:"handle"`
+	Vendor          string    `json:"vendor"`
+	ProductType     string    `json:"productType"`
+	Status          string    `json:"status"`
+	Tags            []string  `json:"tags"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+
+	Options []struct {
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		Position     int    `json:"position"`
+		OptionValues []struct {
+			Name string `json:"name"`
+		} `json:"optionValues"`
+	} `json:"options"`
+	Variants connection[struct {
+		ID                string  `json:"id"`
+		Title             string  `json:"title"`
+		SKU               *string `json:"sku"`
+		Barcode           *string `json:"barcode"`
+		Price             string  `json:"price"`
+		CompareAtPrice    *string `json:"compareAtPrice"`
+		InventoryQuantity *int    `json:"inventoryQuantity"`
+		SelectedOptions   []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"selectedOptions"`
+	}] `json:"variants"`
+	// Media which are not images are selected as empty objects
+	Media connection[struct {
+		ID    string `json:"id"`
+		Alt   string `json:"alt"`
+		Image *struct {
+			URL string `json:"url"`
+		} `json:"image"`
+	}] `json:"media"`
+	Metafields connection[entity.Metafield] `json:"metafields"`
 }
 
-func (s *shopifyAPI) CreateProducts(ctx context.Context) error {
+func (p product) toEntity() entity.Product {
+	product := entity.Product{
+		ID:              p.ID,
+		Title:           p.Title,
+		DescriptionHTML: p.DescriptionHTML,
+		Handle:          p.Handle,
+		Vendor:          p.Vendor,
+		ProductType:     p.ProductType,
+		Status:          p.Status,
+		Tags:            p.Tags,
+		Metafields:      p.Metafields.Nodes,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+
+	for _, o := range p.Options {
+		option := entity.ProductOption{
+			ID:       o.ID,
+			Name:     o.Name,
+			Position: o.Position,
+		}
+		for _, value := range o.OptionValues {
+			option.Values = append(option.Values, value.Name)
+		}
+		product.Options = append(product.Options, option)
+	}
+
+	for _, v := range p.Variants.Nodes {
+		variant := entity.ProductVariant{
+			ID:                v.ID,
+			Title:             v.Title,
+			SKU:               stringValue(v.SKU),
+			Barcode:           stringValue(v.Barcode),
+			Price:             v.Price,
+			CompareAtPrice:    stringValue(v.CompareAtPrice),
+			InventoryQuantity: v.InventoryQuantity,
+			Options:           make(map[string]string, len(v.SelectedOptions)),
+		}
+		for _, selected := range v.SelectedOptions {
+			variant.Options[selected.Name] = selected.Value
+		}
+		product.Variants = append(product.Variants, variant)
+	}
+
+	for _, m := range p.Media.Nodes {
+		if m.ID == "" || m.Image == nil {
+			continue
+		}
+		product.Images = append(product.Images, entity.ProductImage{
+			ID:      m.ID,
+			URL:     m.Image.URL,
+			AltText: m.Alt,
+		})
+	}
+
+	return product
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+const productsQuery = `
+query products($first: Int!, $after: String, $query: String) {
+  products(first: $first, after: $after, query: $query) {
+    nodes {` + productFields + `
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}`
+
+type productsData struct {
+	Products connection[product] `json:"products"`
+}
+
+func (s *shopifyAPI) ListProducts(ctx context.Context, query string, first int) iter.Seq2[entity.Product, error] {
 	logger := s.logger.
-		Named("CreateProducts").
-		WithContext(ctx)
+		Named("ListProducts").
+		WithContext(ctx).
+		With("query", query, "first", first)
+
+	variables := map[string]any{}
+	if query != "" {
+		variables["query"] = query
+	}
+	products := paginateGraphQL(ctx, s, productsQuery, variables, first, func(data *productsData) *connection[product] {
+		return &data.Products
+	})
+	return convertItems(products, product.toEntity, func(err error) error {
+		logger.Error("failed to list products", "err", err)
+		return fmt.Errorf("failed to list products: %w", err)
+	})
+}
+
+const productQuery = `
+query product($id: ID!) {
+  product(id: $id) {` + productFields + productDetailsFields + `
+  }
+}`
+
+type productData struct {
+	Product *product `json:"product"`
+}
+
+func (s *shopifyAPI) GetProduct(ctx context.Context, productID string) (*entity.Product, error) {
+	logger := s.logger.
+		Named("GetProduct").
+		WithContext(ctx).
+		With("productID", productID)
+
+	res, err := doGraphQL[productData](ctx, s, productQuery, map[string]any{
+		"id": productID,
+	})
+	if err != nil {
+		logger.Error("failed to get product", "err", err)
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if res.Data.Product == nil {
+		logger.Info("product is not found")
+		return nil, nil
+	}
+
+	product := res.Data.Product.toEntity()
+	return &product, nil
+}
+
+// productSetMutation creates product if identifier is not given, or replaces the identified one.
+// It's synchronous, so the product is returned with its variants and media.
+const productSetMutation = `
+mutation productSet($identifier: ProductSetIdentifiers, $input: ProductSetInput!) {
+  productSet(identifier: $identifier, input: $input, synchronous: true) {
+    product {` + productFields + productDetailsFields + `
+    }
+    userErrors {
+      field
+      message
+      code
+    }
+  }
+}`
+
+type productSetIdentifiers struct {
+	ID string `json:"id"`
+}
+
+type productSetInput struct {
+	Title           string                   `json:"title"`
+	DescriptionHTML string                   `json:"descriptionHtml"`
+	Vendor          string                   `json:"vendor"`
+	ProductType     string                   `json:"productType"`
+	Status          string                   `json:"status,omitempty"`
+	Tags            []string                 `json:"tags"`
+	ProductOptions  []optionSetInput         `json:"productOptions,omitempty"`
+	Variants        []productVariantSetInput `json:"variants,omitempty"`
+	Files           []fileSetInput           `json:"files"`
+	Metafields      []service.MetafieldInput `json:"metafields,omitempty"`
+}
+
+type optionSetInput struct {
+	Name     string                `json:"name"`
+	Position int                   `json:"position"`
+	Values   []optionValueSetInput `json:"values"`
+}
+
+type optionValueSetInput struct {
+	Name string `json:"name"`
+}
+
+type productVariantSetInput struct {
+	ID             string                    `json:"id,omitempty"`
+	OptionValues   []variantOptionValueInput `json:"optionValues"`
+	Price          string                    `json:"price"`
+	CompareAtPrice *string                   `json:"compareAtPrice"`
+	Barcode        *string                   `json:"barcode"`
+	InventoryItem  struct {
+		SKU string `json:"sku"`
+	} `json:"inventoryItem"`
+}
+
+type variantOptionValueInput struct {
+	OptionName string `json:"optionName"`
+	Name       string `json:"name"`
+}
+
+type fileSetInput struct {
+	ID             string `json:"id,omitempty"`
+	OriginalSource string `json:"originalSource,omitempty"`
+	Alt            string `json:"alt"`
+	ContentType    string `json:"contentType,omitempty"`
+}
+
+// Products without options have the default option, and their single variant has its value.
+const (
+	defaultProductOption      = "Title"
+	defaultProductOptionValue = "Default Title"
+)
+
+func newProductSetInput(input *service.ProductInput) productSetInput {
+	setInput := productSetInput{
+		Title:           input.Title,
+		DescriptionHTML: input.DescriptionHTML,
+		Vendor:          input.Vendor,
+		ProductType:     input.ProductType,
+		Status:          input.Status,
+		Tags:            input.Tags,
+		Metafields:      input.Metafields,
+		// Files are always sent, so images which are not given are removed
+		Files: []fileSetInput{},
+	}
+	if setInput.Tags == nil {
+		setInput.Tags = []string{}
+	}
 
-	for range service.DEFAULT_PRODUCT_COUNT {
-		title := generateRandomProductTitle()
-		product := &createProductRequestBody{
-			Product: &product{
-				Title:       title,
-				BodyHTML:    fmt.Sprintf("<p>Product %s</p>", title),
-				Vendor:      "Vendor",
-				ProductType: "Type",
-				Status:      "active",
-			},
+	options := input.Options
+	variants := input.Variants
+	// Variants have to be given with values of options
+	if len(options) == 0 && len(variants) > 0 {
+		options = []service.ProductOptionInput{{Name: defaultProductOption, Values: []string{defaultProductOptionValue}}}
+		variants = slices.Clone(variants)
+		for i := range variants {
+			variants[i].Options = map[string]string{defaultProductOption: defaultProductOptionValue}
 		}
-		res, err := s.client.R().
-			SetBody(product).
-			Post("/admin/api/2022-07/products.json")
-		if err != nil {
-			logger.Error("failed to create product", "err", err)
-			return err
+	}
+
+	for i, option := range options {
+		optionInput := optionSetInput{Name: option.Name, Position: i + 1}
+		for _, value := range option.Values {
+			optionInput.Values = append(optionInput.Values, optionValueSetInput{Name: value})
+		}
+		setInput.ProductOptions = append(setInput.ProductOptions, optionInput)
+	}
+
+	for _, variant := range variants {
+		variantInput := productVariantSetInput{
+			ID:    variant.ID,
+			Price: variant.Price,
+		}
+		if variant.CompareAtPrice != "" {
+			variantInput.CompareAtPrice = &variant.CompareAtPrice
 		}
-		if res.StatusCode() != http.StatusCreated {
-			logger.Error("failed to create product", "status", res.StatusCode())
-			return fmt.Errorf("failed to create product")
+		if variant.Barcode != "" {
+			variantInput.Barcode = &variant.Barcode
 		}
+		variantInput.InventoryItem.SKU = variant.SKU
+		for _, option := range options {
+			variantInput.OptionValues = append(variantInput.OptionValues, variantOptionValueInput{
+				OptionName: option.Name,
+				Name:       variant.Options[option.Name],
+			})
+		}
+		setInput.Variants = append(setInput.Variants, variantInput)
 	}
-	logger.Info("created products")
 
-	return nil
+	for _, image := range input.Images {
+		if image.ID != "" {
+			setInput.Files = append(setInput.Files, fileSetInput{ID: image.ID, Alt: image.AltText})
+			continue
+		}
+		setInput.Files = append(setInput.Files, fileSetInput{
+			OriginalSource: image.URL,
+			Alt:            image.AltText,
+			ContentType:    "IMAGE",
+		})
+	}
+
+	return setInput
 }
 
-func generateRandomProductTitle() string {
-	return fmt.Sprintf("Product %d", rand.Intn(1000))
+type productSetData struct {
+	ProductSet struct {
+		Product *product `json:"product"`
+		userErrors
+	} `json:"productSet"`
 }
 
-func (s *shopifyAPI) GetProductsCount(ctx context.Context) (int, error) {
+// setProduct creates product, or replaces it if productID is given.
+func (s *shopifyAPI) setProduct(ctx context.Context, productID string, input *service.ProductInput) (*entity.Product, error) {
+	variables := map[string]any{
+		"input": newProductSetInput(input),
+	}
+	if productID != "" {
+		variables["identifier"] = productSetIdentifiers{ID: productID}
+	}
+
+	res, err := doGraphQL[productSetData](ctx, s, productSetMutation, variables)
+	if err != nil {
+		return nil, err
+	}
+	payload := res.Data.ProductSet
+	for _, userErr := range payload.UserErrors {
+		if userErr.Code == "PRODUCT_DOES_NOT_EXIST" {
+			return nil, service.ErrProductNotFound
+		}
+	}
+	err = payload.err()
+	if err != nil {
+		return nil, err
+	}
+	if payload.Product == nil {
+		return nil, fmt.Errorf("response doesn't contain product")
+	}
+
+	product := payload.Product.toEntity()
+	return &product, nil
+}
+
+func (s *shopifyAPI) CreateProduct(ctx context.Context, input *service.ProductInput) (*entity.Product, error) {
 	logger := s.logger.
-		Named("GetProductsCount").
+		Named("CreateProduct").
 		WithContext(ctx)
 
-	var responseBody struct {
-		Count int `json:"count"`
+	product, err := s.setProduct(ctx, "", input)
+	if err != nil {
+		logger.Error("failed to create product", "err", err)
+		return nil, fmt.Errorf("failed to create product: %w", err)
+	}
+
+	logger.Info("created product", "productID", product.ID)
+	return product, nil
+}
+
+func (s *shopifyAPI) UpdateProduct(ctx context.Context, productID string, input *service.ProductInput) (*entity.Product, error) {
+	logger := s.logger.
+		Named("UpdateProduct").
+		WithContext(ctx).
+		With("productID", productID)
+
+	product, err := s.setProduct(ctx, productID, input)
+	if err != nil {
+		if errors.Is(err, service.ErrProductNotFound) {
+			logger.Info(err.Error())
+			return nil, err
+		}
+		logger.Error("failed to update product", "err", err)
+		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
 
-	resp, err := s.client.R().
-		SetResult(&responseBody).
-		Get("/admin/api/2022-07/products/count.json")
+	logger.Info("updated product")
+	return product, nil
+}
+
+const productDeleteMutation = `
+mutation productDelete($input: ProductDeleteInput!) {
+  productDelete(input: $input) {
+    deletedProductId
+    userErrors {
+      field
+      message
+    }
+  }
+}`
+
+type productDeleteData struct {
+	ProductDelete struct {
+		DeletedProductID *string `json:"deletedProductId"`
+		userErrors
+	} `json:"productDelete"`
+}
+
+func (s *shopifyAPI) DeleteProduct(ctx context.Context, productID string) error {
+	logger := s.logger.
+		Named("DeleteProduct").
+		WithContext(ctx).
+		With("productID", productID)
+
+	res, err := doGraphQL[productDeleteData](ctx, s, productDeleteMutation, map[string]any{
+		"input": map[string]any{"id": productID},
+	})
 	if err != nil {
-		logger.Error("failed to get products count", "err", err)
-		return 0, err
+		logger.Error("failed to delete product", "err", err)
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	payload := res.Data.ProductDelete
+	// Errors of productDelete have no code, product which doesn't exist is rejected by its id
+	for _, userErr := range payload.UserErrors {
+		if slices.Equal(userErr.Field, []string{"id"}) {
+			logger.Info("product is not found")
+			return service.ErrProductNotFound
+		}
 	}
+	err = payload.err()
+	if err != nil {
+		logger.Info("product deletion is rejected", "err", err)
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+
+	logger.Info("deleted product")
+	return nil
+}
+
+const productsCountQuery = `
+query productsCount {
+  productsCount {
+    count
+  }
+}`
+
+type productsCountData struct {
+	ProductsCount struct {
+		Count int `json:"count"`
+	} `json:"productsCount"`
+}
 
-	if resp.StatusCode() != 200 {
-		logger.Error("failed to get products count", "status", resp.StatusCode())
-		return 0, fmt.Errorf("failed to get products count")
+func (s *shopifyAPI) GetProductsCount(ctx context.Context) (int, error) {
+	logger := s.logger.
+		Named("GetProductsCount").
+		WithContext(ctx)
+
+	res, err := doGraphQL[productsCountData](ctx, s, productsCountQuery, nil)
+	if err != nil {
+		logger.Error("failed to get products count", "err", err)
+		return 0, fmt.Errorf("failed to get products count: %w", err)
 	}
 
-	return responseBody.Count, nil
+	return res.Data.ProductsCount.Count, nil
 }
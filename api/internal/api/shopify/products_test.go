@@ -0,0 +1,131 @@
+package shopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/antflydb/shopify-app-template-go/config"
+	"github.com/antflydb/shopify-app-template-go/internal/service"
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+	"github.com/go-resty/resty/v2"
+)
+
+// userErrorFields are fields of user errors of mutations as recorded from the Admin API schema,
+// e.g. productDelete returns UserError, which has no code unlike ProductSetUserError.
+var userErrorFields = map[string][]string{
+	"productDelete": {"field", "message"},
+	"productSet":    {"field", "message", "code"},
+}
+
+var (
+	mutationFieldRegexp   = regexp.MustCompile(`mutation\s+\w+\([^)]*\)\s*\{\s*(\w+)`)
+	userErrorsFieldRegexp = regexp.MustCompile(`userErrors\s*\{([^}]*)\}`)
+)
+
+// newTestGraphQLServer returns server of Admin GraphQL API responding to every mutation with data.
+// Like Admin API, it rejects mutations selecting fields their user errors don't have.
+func newTestGraphQLServer(t *testing.T, data string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			t.Errorf("failed to decode graphql request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if match := mutationFieldRegexp.FindStringSubmatch(req.Query); match != nil {
+			fields, ok := userErrorFields[match[1]]
+			if !ok {
+				t.Errorf("schema of %s is not recorded", match[1])
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			for _, selection := range userErrorsFieldRegexp.FindAllStringSubmatch(req.Query, -1) {
+				for _, field := range strings.Fields(selection[1]) {
+					if !slices.Contains(fields, field) {
+						_, _ = w.Write([]byte(`{"errors":[{"message":"Field '` + field + `' doesn't exist on type of ` + match[1] + ` user errors"}]}`))
+						return
+					}
+				}
+			}
+		}
+
+		_, _ = w.Write([]byte(`{"data":` + data + `}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestAPI(server *httptest.Server) *shopifyAPI {
+	cfg := &config.Config{}
+	cfg.Shopify.ApiVersion = "2025-10"
+
+	logger := logging.NewZap("error")
+	s := &shopifyAPI{
+		logger:       logger,
+		cfg:          cfg,
+		deprecations: newDeprecations(logger),
+		rateLimiters: newRateLimiters(),
+	}
+	s.client = resty.New().
+		SetBaseURL(server.URL).
+		SetHeader("Content-Type", "application/json").
+		OnAfterResponse(s.checkAPIVersion)
+	return s
+}
+
+func TestDeleteProduct(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{
+			name: "deleted",
+			data: `{"productDelete":{"deletedProductId":"gid://shopify/Product/1","userErrors":[]}}`,
+		},
+		{
+			name:    "not found",
+			data:    `{"productDelete":{"deletedProductId":null,"userErrors":[{"field":["id"],"message":"Product does not exist"}]}}`,
+			wantErr: service.ErrProductNotFound,
+		},
+		{
+			name:    "rejected",
+			data:    `{"productDelete":{"deletedProductId":null,"userErrors":[{"field":null,"message":"Product can't be deleted"}]}}`,
+			wantErr: service.APIUserErrors{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestAPI(newTestGraphQLServer(t, tt.data))
+
+			err := s.DeleteProduct(context.Background(), "gid://shopify/Product/1")
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case service.APIUserErrors:
+				if !errors.As(err, &want) || errors.Is(err, service.ErrProductNotFound) {
+					t.Fatalf("expected user errors, got %v", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
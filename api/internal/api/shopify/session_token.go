@@ -0,0 +1,156 @@
+package shopify
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is a struct that represents the JWT claims payload of session token.
+// https://shopify.dev/docs/apps/build/authentication-authorization/session-tokens#anatomy-of-a-session-token
+type Claims struct {
+	// Dest is the shop's domain, e.g. https://example.myshopify.com
+	Dest string `json:"dest"`
+	// Sid is ID of the user's Shopify admin session.
+	Sid string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// sessionTokenValidator validates App Bridge session tokens.
+type sessionTokenValidator struct {
+	apps   *entity.Apps
+	leeway time.Duration
+	// used tracks IDs of accepted tokens, it's nil if tokens can be reused
+	used *usedTokens
+	now  func() time.Time
+}
+
+func newSessionTokenValidator(apps *entity.Apps, leeway time.Duration, replayProtection bool) *sessionTokenValidator {
+	v := &sessionTokenValidator{
+		apps:   apps,
+		leeway: leeway,
+		now:    time.Now,
+	}
+	if replayProtection {
+		v.used = &usedTokens{expiresAt: make(map[string]time.Time)}
+	}
+	return v
+}
+
+// sessionToken is a validated session token.
+type sessionToken struct {
+	claims *Claims
+	// storeName is the store the token is issued for
+	storeName string
+	// app is the app the token is issued for
+	app *entity.App
+}
+
+// validate verifies session token and returns its claims, the store and the app it's issued for.
+// Token has to be signed with HS256 by the secret of the app in aud, issued by the shop in dest,
+// and valid at the moment, allowing for the configured clock skew.
+// https://shopify.dev/docs/apps/build/authentication-authorization/session-tokens/set-up-session-tokens#verify-the-session-token
+func (v *sessionTokenValidator) validate(tokenString string) (*sessionToken, error) {
+	var app *entity.App
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		// Token is verified with secrets of the app it's issued for
+		if len(claims.Audience) != 1 {
+			return nil, errors.New("JWT token has to contain single aud value")
+		}
+		app = v.apps.ByAPIKey(claims.Audience[0])
+		if app == nil {
+			return nil, errors.New("JWT token contains incorrect audience value")
+		}
+		if len(app.Secrets) == 0 {
+			return nil, errors.New("api secret is not configured")
+		}
+
+		// Token signed with the previous secret is accepted while the secret is rotated
+		keys := jwt.VerificationKeySet{}
+		for _, secret := range app.Secrets {
+			keys.Keys = append(keys.Keys, []byte(secret))
+		}
+		return keys, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithTimeFunc(v.now),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session token: %w", err)
+	}
+
+	storeName, err := getStoreName(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Issuer is the shop's admin, e.g. https://example.myshopify.com/admin
+	if claims.Issuer != claims.Dest+"/admin" {
+		return nil, errors.New("JWT token contains incorrect issuer value")
+	}
+
+	if v.used != nil {
+		if claims.ID == "" {
+			return nil, errors.New("JWT token doesn't contain jti value")
+		}
+		// Token is remembered until it can't be accepted anymore
+		if !v.used.add(claims.ID, claims.ExpiresAt.Add(v.leeway), v.now()) {
+			return nil, errors.New("JWT token has already been used")
+		}
+	}
+
+	return &sessionToken{
+		claims:    claims,
+		storeName: storeName,
+		app:       app,
+	}, nil
+}
+
+// getStoreName returns name of the store session token is issued for.
+func getStoreName(claims *Claims) (string, error) {
+	dest, err := url.Parse(claims.Dest)
+	if err != nil || dest.Scheme != "https" || dest.Path != "" || dest.User != nil || dest.RawQuery != "" {
+		return "", errors.New("JWT token contains invalid dest value")
+	}
+	shop, err := entity.ParseShopDomain(dest.Host)
+	if err != nil {
+		return "", fmt.Errorf("JWT token contains invalid dest value: %w", err)
+	}
+
+	return shop.String(), nil
+}
+
+// usedTokens tracks IDs of session tokens accepted by this instance of the app until they expire.
+type usedTokens struct {
+	mu        sync.Mutex
+	expiresAt map[string]time.Time
+}
+
+// add records token ID and reports whether it wasn't used before.
+func (u *usedTokens) add(id string, expiresAt, now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if usedUntil, ok := u.expiresAt[id]; ok && now.Before(usedUntil) {
+		return false
+	}
+
+	// Tokens are short-lived, so expired ones are forgotten on every insert
+	for usedID, usedUntil := range u.expiresAt {
+		if !now.Before(usedUntil) {
+			delete(u.expiresAt, usedID)
+		}
+	}
+	u.expiresAt[id] = expiresAt
+
+	return true
+}
@@ -0,0 +1,302 @@
+package shopify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testAPIKey            = "test-api-key"
+	testAPISecret         = "test-api-secret"
+	testAPISecretPrevious = "test-api-secret-previous"
+	testShop              = "example.myshopify.com"
+)
+
+var testNow = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestValidator(t *testing.T, replayProtection bool) *sessionTokenValidator {
+	t.Helper()
+
+	apps, err := entity.NewApps(&entity.App{
+		APIKey:  testAPIKey,
+		Secrets: []string{testAPISecret, testAPISecretPrevious},
+	}, "staging:staging-api-key:staging-api-secret")
+	if err != nil {
+		t.Fatalf("failed to create apps: %v", err)
+	}
+
+	v := newSessionTokenValidator(apps, 5*time.Second, replayProtection)
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+// validClaims returns claims of a session token issued by App Bridge a few seconds ago.
+func validClaims() *Claims {
+	return &Claims{
+		Dest: "https://" + testShop,
+		Sid:  "session-id",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://" + testShop + "/admin",
+			Subject:   "42",
+			Audience:  jwt.ClaimStrings{testAPIKey},
+			ExpiresAt: jwt.NewNumericDate(testNow.Add(50 * time.Second)),
+			NotBefore: jwt.NewNumericDate(testNow.Add(-10 * time.Second)),
+			IssuedAt:  jwt.NewNumericDate(testNow.Add(-10 * time.Second)),
+			ID:        "token-id",
+		},
+	}
+}
+
+func mintToken(t *testing.T, method jwt.SigningMethod, key any, claims *Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to mint token: %v", err)
+	}
+	return token
+}
+
+func TestSessionTokenValidatorValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantApp string
+		wantErr bool
+	}{
+		{
+			name: "valid token",
+			token: func(t *testing.T) string {
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), validClaims())
+			},
+		},
+		{
+			name: "signed with previous secret",
+			token: func(t *testing.T) string {
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecretPrevious), validClaims())
+			},
+		},
+		{
+			name: "issued for another app",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.Audience = jwt.ClaimStrings{"staging-api-key"}
+				return mintToken(t, jwt.SigningMethodHS256, []byte("staging-api-secret"), claims)
+			},
+			wantApp: "staging",
+		},
+		{
+			name: "signed with secret of another app",
+			token: func(t *testing.T) string {
+				return mintToken(t, jwt.SigningMethodHS256, []byte("staging-api-secret"), validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired within leeway",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(testNow.Add(-3 * time.Second))
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+		},
+		{
+			name: "not valid yet within leeway",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.NotBefore = jwt.NewNumericDate(testNow.Add(3 * time.Second))
+				claims.IssuedAt = jwt.NewNumericDate(testNow.Add(3 * time.Second))
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(testNow.Add(-10 * time.Second))
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "not valid yet",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.NotBefore = jwt.NewNumericDate(testNow.Add(10 * time.Second))
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "issued in the future",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.IssuedAt = jwt.NewNumericDate(testNow.Add(10 * time.Second))
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing expiration",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.ExpiresAt = nil
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "signed with another secret",
+			token: func(t *testing.T) string {
+				return mintToken(t, jwt.SigningMethodHS256, []byte("another-secret"), validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "signed with another hmac algorithm",
+			token: func(t *testing.T) string {
+				return mintToken(t, jwt.SigningMethodHS512, []byte(testAPISecret), validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsigned",
+			token: func(t *testing.T) string {
+				return mintToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "issued for unknown app",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.Audience = jwt.ClaimStrings{"another-api-key"}
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "issued by another shop",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.Issuer = "https://another.myshopify.com/admin"
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "issuer containing dest",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.Issuer = "https://" + testShop + ".evil.com/admin"
+				claims.Dest = "https://" + testShop
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "dest is not a shop domain",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				claims.Dest = "https://example.com"
+				claims.Issuer = "https://example.com/admin"
+				return mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed token",
+			token: func(t *testing.T) string {
+				return "not-a-token"
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := newTestValidator(t, false).validate(tt.token(t))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token.storeName != testShop {
+				t.Errorf("store name = %q, want %q", token.storeName, testShop)
+			}
+			wantApp := tt.wantApp
+			if wantApp == "" {
+				wantApp = entity.DefaultAppHandle
+			}
+			if token.app.Handle != wantApp {
+				t.Errorf("app = %q, want %q", token.app.Handle, wantApp)
+			}
+			if token.claims.Subject != "42" || token.claims.Sid != "session-id" {
+				t.Errorf("unexpected claims: %+v", token.claims)
+			}
+		})
+	}
+}
+
+func TestSessionTokenValidatorReplayProtection(t *testing.T) {
+	token := mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), validClaims())
+
+	t.Run("disabled", func(t *testing.T) {
+		v := newTestValidator(t, false)
+		for i := 0; i < 2; i++ {
+			if _, err := v.validate(token); err != nil {
+				t.Fatalf("attempt %d: unexpected error: %v", i+1, err)
+			}
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		v := newTestValidator(t, true)
+		if _, err := v.validate(token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := v.validate(token); err == nil {
+			t.Fatal("expected replayed token to be rejected")
+		}
+
+		other := validClaims()
+		other.ID = "another-token-id"
+		if _, err := v.validate(mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), other)); err != nil {
+			t.Fatalf("unexpected error for another token: %v", err)
+		}
+	})
+
+	t.Run("missing jti", func(t *testing.T) {
+		claims := validClaims()
+		claims.ID = ""
+		if _, err := newTestValidator(t, true).validate(mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), claims)); err == nil {
+			t.Fatal("expected token without jti to be rejected")
+		}
+	})
+
+	t.Run("forgotten after expiry", func(t *testing.T) {
+		v := newTestValidator(t, true)
+		if _, err := v.validate(token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Token can't be replayed once it has expired, so its ID is forgotten
+		v.now = func() time.Time { return testNow.Add(time.Minute) }
+		other := validClaims()
+		other.ID = "another-token-id"
+		other.ExpiresAt = jwt.NewNumericDate(testNow.Add(2 * time.Minute))
+		if _, err := v.validate(mintToken(t, jwt.SigningMethodHS256, []byte(testAPISecret), other)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := v.used.expiresAt["token-id"]; ok {
+			t.Error("expired token id is still tracked")
+		}
+	})
+}
@@ -2,7 +2,13 @@ package shopify
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/antflydb/shopify-app-template-go/config"
@@ -15,6 +21,7 @@ import (
 
 type Options struct {
 	Config *config.Config
+	Apps   *entity.Apps
 	Logger logging.Logger
 }
 
@@ -25,123 +32,255 @@ type shopifyAPI struct {
 	logger  logging.Logger
 	cfg     *config.Config
 	retries int
+	// app is the app requests are made by
+	app *entity.App
+	// sessionTokens is shared by all instances, so used session tokens are tracked across them
+	sessionTokens *sessionTokenValidator
+	// deprecations is shared by all instances, so deprecated calls are counted across them
+	deprecations *deprecations
+	// rateLimiters is shared by all instances, so requests to a store are paced together
+	rateLimiters *rateLimiters
+	// limiter paces requests to the store the instance sends requests to, it's nil for other instances
+	limiter *rateLimiter
 }
 
 func NewAPI(opts Options) *shopifyAPI {
 	restyClient := resty.New()
 
 	return &shopifyAPI{
-		logger: opts.Logger.Named("shopifyAPI"),
-		cfg:    opts.Config,
-		client: restyClient,
+		logger:        opts.Logger.Named("shopifyAPI"),
+		cfg:           opts.Config,
+		client:        restyClient,
+		retries:       opts.Config.Shopify.MaxRetries,
+		app:           opts.Apps.Default(),
+		sessionTokens: newSessionTokenValidator(opts.Apps, opts.Config.Shopify.SessionTokenLeeway, opts.Config.Shopify.SessionTokenReplayProtection),
+		deprecations:  newDeprecations(opts.Logger.Named("shopifyAPI")),
+		rateLimiters:  newRateLimiters(),
 	}
 }
-func (s *shopifyAPI) WithConfig(ctx context.Context, store *entity.Store) service.PlatformAPI {
-	var h *resty.Client
 
-	if s.retries != 0 {
-		c := retryablehttp.NewClient()
-		c.RetryMax = s.retries
-		c.RetryWaitMax = time.Second * 30
-		h = resty.NewWithClient(c.StandardClient())
-	} else {
-		h = resty.New()
+// newClient returns resty client sending requests with the transport,
+// retrying failed requests if retries are configured.
+func (s *shopifyAPI) newClient(transport http.RoundTripper) *resty.Client {
+	if s.retries == 0 {
+		return resty.New().SetTransport(transport)
 	}
 
-	h = h.
-		SetBaseURL(fmt.Sprintf(`https://%s`, store.Name)).
-		SetHeader("X-Shopify-Access-Token", store.AccessToken).
-		SetHeader("Content-Type", "application/json")
+	c := retryablehttp.NewClient()
+	c.HTTPClient.Transport = transport
+	c.RetryMax = s.retries
+	c.RetryWaitMax = time.Second * 30
+	c.CheckRetry = checkRetry
+	// Caller gets the last response if all attempts fail
+	c.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	c.Logger = s.logger.Named("retryablehttp")
+	return resty.NewWithClient(c.StandardClient())
+}
 
-	return &shopifyAPI{
-		client:  h,
-		logger:  s.logger,
-		cfg:     s.cfg,
-		retries: s.retries,
+// checkRetry retries requests which failed because of connection errors or server errors.
+// Requests changing data are retried only if they weren't sent, i.e. connection to the store failed,
+// and rate limited requests are retried by rateLimitTransport.
+func checkRetry(ctx context.Context, res *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if res != nil && (res.StatusCode == http.StatusTooManyRequests || !isIdempotent(res.Request.Method)) {
+		return false, nil
+	}
+	if res == nil && !isIdempotent(requestMethod(err)) && !isDialErr(err) {
+		return false, nil
 	}
+	return retryablehttp.DefaultRetryPolicy(ctx, res, err)
 }
 
-func (s *shopifyAPI) WithSessionToken(ctx context.Context, store *entity.Store, sessionToken string) service.PlatformAPI {
-	var h *resty.Client
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	// GraphQL queries are sent with POST as well, but they can't be told apart from mutations here
+	return false
+}
 
-	if s.retries != 0 {
-		c := retryablehttp.NewClient()
-		c.RetryMax = s.retries
-		c.RetryWaitMax = time.Second * 30
-		h = resty.NewWithClient(c.StandardClient())
-	} else {
-		h = resty.New()
+// requestMethod returns method of the request which failed with the error returned by http.Client,
+// or empty string if it's unknown.
+func requestMethod(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// Op is the method with only first letter in upper case, e.g. Post
+		return strings.ToUpper(urlErr.Op)
 	}
+	return ""
+}
+
+// isDialErr reports whether the error is failure to connect, so nothing of the request was sent.
+func isDialErr(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// newStoreClient returns client sending requests to the store.
+// Requests are paced by the store's rate limiter and responses are checked for use of deprecated API.
+func (s *shopifyAPI) newStoreClient(storeName string) *resty.Client {
+	h := s.newClient(&rateLimitTransport{
+		limiter: s.rateLimiters.get(s.app.Handle, storeName),
+		base:    http.DefaultTransport,
+	}).
+		SetHeader("Content-Type", "application/json").
+		OnAfterResponse(s.checkAPIVersion)
 
-	// Exchange session token for access token
-	accessToken, err := s.exchangeSessionToken(ctx, store.Name, sessionToken)
+	// Requests are sent only to validated shop domains
+	shop, err := entity.ParseShopDomain(storeName)
 	if err != nil {
-		s.logger.Error("failed to exchange session token", "err", err)
-		// Return client without access token - requests will fail but won't crash
-		h = h.
-			SetBaseURL(fmt.Sprintf(`https://%s`, store.Name)).
-			SetHeader("Content-Type", "application/json")
+		s.logger.Error("invalid store name, requests to store will fail", "storeName", storeName)
 	} else {
-		h = h.
-			SetBaseURL(fmt.Sprintf(`https://%s`, store.Name)).
-			SetHeader("X-Shopify-Access-Token", accessToken).
-			SetHeader("Content-Type", "application/json")
+		h = h.SetBaseURL(shop.URL(""))
 	}
 
+	return h
+}
+
+// withClient returns a new instance of shopifyAPI sending requests with the client.
+func (s *shopifyAPI) withClient(h *resty.Client) *shopifyAPI {
 	return &shopifyAPI{
-		client:  h,
-		logger:  s.logger,
-		cfg:     s.cfg,
-		retries: s.retries,
+		client:        h,
+		logger:        s.logger,
+		cfg:           s.cfg,
+		retries:       s.retries,
+		app:           s.app,
+		sessionTokens: s.sessionTokens,
+		deprecations:  s.deprecations,
+		rateLimiters:  s.rateLimiters,
+		limiter:       s.limiter,
+	}
+}
+
+// withStoreClient returns a new instance of shopifyAPI sending requests to the store with the client.
+func (s *shopifyAPI) withStoreClient(storeName string, h *resty.Client) *shopifyAPI {
+	api := s.withClient(h)
+	api.limiter = s.rateLimiters.get(s.app.Handle, storeName)
+	return api
+}
+
+func (s *shopifyAPI) WithApp(ctx context.Context, app *entity.App) service.PlatformAPI {
+	api := s.withClient(s.client)
+	api.app = app
+	return api
+}
+
+func (s *shopifyAPI) WithConfig(ctx context.Context, store *entity.Store) service.PlatformAPI {
+	return s.withStoreClient(store.Name, s.newStoreClient(store.Name).SetHeader("X-Shopify-Access-Token", store.AccessToken))
+}
+
+func (s *shopifyAPI) WithSession(ctx context.Context, session *entity.Session) service.PlatformAPI {
+	return s.withStoreClient(session.Shop, s.newStoreClient(session.Shop).SetHeader("X-Shopify-Access-Token", session.AccessToken))
+}
+
+// associatedUser is the user online access token is issued to.
+type associatedUser struct {
+	ID           int64  `json:"id"`
+	Email        string `json:"email"`
+	AccountOwner bool   `json:"account_owner"`
+	Collaborator bool   `json:"collaborator"`
+}
+
+func (u associatedUser) toEntity(scope string) *entity.AssociatedUser {
+	return &entity.AssociatedUser{
+		ID:           strconv.FormatInt(u.ID, 10),
+		Email:        u.Email,
+		AccountOwner: u.AccountOwner,
+		Collaborator: u.Collaborator,
+		Scope:        entity.ParseAccessScopes(scope).String(),
 	}
 }
 
-// exchangeSessionToken exchanges a session token for an access token
-// https://shopify.dev/docs/apps/auth/oauth/session-tokens/getting-started#step-3-make-authenticated-requests
-func (s *shopifyAPI) exchangeSessionToken(ctx context.Context, storeName, sessionToken string) (string, error) {
-	logger := s.logger.Named("exchangeSessionToken").WithContext(ctx)
+// Requested token types of token exchange.
+const (
+	tokenTypeOnlineAccessToken  = "urn:shopify:params:oauth:token-type:online-access-token"
+	tokenTypeOfflineAccessToken = "urn:shopify:params:oauth:token-type:offline-access-token"
+)
+
+// ExchangeSessionToken exchanges a session token for an access token
+// https://shopify.dev/docs/apps/build/authentication-authorization/access-tokens/token-exchange
+func (s *shopifyAPI) ExchangeSessionToken(ctx context.Context, storeName, sessionToken string, mode entity.AccessMode) (*service.APIExchangeSessionTokenOutput, error) {
+	logger := s.logger.
+		Named("ExchangeSessionToken").
+		WithContext(ctx).
+		With("storeName", storeName, "mode", mode)
+
+	shop, err := entity.ParseShopDomain(storeName)
+	if err != nil {
+		logger.Info("invalid shop domain")
+		return nil, err
+	}
 
 	type tokenExchangeRequest struct {
-		ClientID     string `json:"client_id"`
-		ClientSecret string `json:"client_secret"`
-		GrantType    string `json:"grant_type"`
-		SubjectToken string `json:"subject_token"`
-		SubjectTokenType string `json:"subject_token_type"`
+		ClientID           string `json:"client_id"`
+		ClientSecret       string `json:"client_secret"`
+		GrantType          string `json:"grant_type"`
+		SubjectToken       string `json:"subject_token"`
+		SubjectTokenType   string `json:"subject_token_type"`
+		RequestedTokenType string `json:"requested_token_type"`
+		Expiring           string `json:"expiring,omitempty"`
 	}
 
 	type tokenExchangeResponse struct {
-		AccessToken string `json:"access_token"`
-		TokenType   string `json:"token_type"`
-		ExpiresIn   int    `json:"expires_in"`
+		AccessToken         string          `json:"access_token"`
+		Scope               string          `json:"scope"`
+		ExpiresIn           int             `json:"expires_in"`
+		AssociatedUserScope string          `json:"associated_user_scope"`
+		AssociatedUser      *associatedUser `json:"associated_user"`
+	}
+
+	requestedTokenType := tokenTypeOfflineAccessToken
+	if mode == entity.AccessModeOnline {
+		requestedTokenType = tokenTypeOnlineAccessToken
 	}
 
 	requestBody := tokenExchangeRequest{
-		ClientID:         s.cfg.Shopify.ApiKey,
-		ClientSecret:     s.cfg.Shopify.ApiSecret,
-		GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
-		SubjectToken:     sessionToken,
-		SubjectTokenType: "urn:ietf:params:oauth:token-type:id_token",
+		ClientID:           s.app.APIKey,
+		ClientSecret:       s.app.Secret(),
+		GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
+		SubjectToken:       sessionToken,
+		SubjectTokenType:   "urn:ietf:params:oauth:token-type:id_token",
+		RequestedTokenType: requestedTokenType,
+	}
+	if mode == entity.AccessModeOffline && s.cfg.Shopify.ExpiringOfflineTokens {
+		requestBody.Expiring = "1"
 	}
 
 	var responseBody tokenExchangeResponse
 
-	client := resty.New()
-	resp, err := client.R().
+	resp, err := resty.New().R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(requestBody).
 		SetResult(&responseBody).
-		Post(fmt.Sprintf("https://%s/admin/oauth/access_token", storeName))
-
+		Post(shop.URL("/admin/oauth/access_token"))
 	if err != nil {
 		logger.Error("failed to make token exchange request", "err", err)
-		return "", fmt.Errorf("failed to exchange session token: %w", err)
+		return nil, fmt.Errorf("failed to exchange session token: %w", err)
 	}
-
-	if resp.StatusCode() != 200 {
+	if resp.StatusCode() != http.StatusOK {
 		logger.Error("token exchange failed", "status", resp.StatusCode(), "response", string(resp.Body()))
-		return "", fmt.Errorf("token exchange failed with status %d", resp.StatusCode())
+		return nil, fmt.Errorf("token exchange failed with status %d", resp.StatusCode())
+	}
+	if responseBody.AccessToken == "" {
+		logger.Error("token exchange response doesn't contain access token")
+		return nil, errors.New("token exchange response doesn't contain access token")
+	}
+
+	output := &service.APIExchangeSessionTokenOutput{
+		AccessToken: responseBody.AccessToken,
+		Scopes:      entity.ParseAccessScopes(responseBody.Scope).String(),
+	}
+	// Online access tokens are issued to the user the session token belongs to
+	if responseBody.AssociatedUser != nil {
+		output.AssociatedUser = responseBody.AssociatedUser.toEntity(responseBody.AssociatedUserScope)
 	}
+	// Offline access tokens expire only if expiring tokens are requested
+	output.ExpiresAt = expiresIn(responseBody.ExpiresIn)
 
-	logger.Info("successfully exchanged session token for access token")
-	return responseBody.AccessToken, nil
+	logger.Info("successfully exchanged session token for access token", "expiresAt", output.ExpiresAt)
+	return output, nil
 }
@@ -0,0 +1,189 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/internal/service"
+	"github.com/go-resty/resty/v2"
+)
+
+// accessTokenResponse is returned by the access token endpoint.
+// Expiring offline access tokens come with a refresh token.
+// https://shopify.dev/docs/apps/build/authentication-authorization/access-tokens/offline-access-tokens
+type accessTokenResponse struct {
+	AccessToken           string `json:"access_token"`
+	Scope                 string `json:"scope"`
+	ExpiresIn             int    `json:"expires_in"`
+	RefreshToken          string `json:"refresh_token"`
+	RefreshTokenExpiresIn int    `json:"refresh_token_expires_in"`
+}
+
+// expiresAt returns time when the access token expires, or nil if it doesn't expire.
+func (r accessTokenResponse) expiresAt() *time.Time {
+	return expiresIn(r.ExpiresIn)
+}
+
+// refreshTokenExpiresAt returns time when the refresh token expires, or nil if it doesn't expire.
+func (r accessTokenResponse) refreshTokenExpiresAt() *time.Time {
+	return expiresIn(r.RefreshTokenExpiresIn)
+}
+
+func expiresIn(seconds int) *time.Time {
+	if seconds <= 0 {
+		return nil
+	}
+	expiresAt := time.Now().Add(time.Duration(seconds) * time.Second)
+	return &expiresAt
+}
+
+func (s *shopifyAPI) RefreshAccessToken(ctx context.Context, storeName, refreshToken string) (*service.APIRefreshAccessTokenOutput, error) {
+	logger := s.logger.
+		Named("RefreshAccessToken").
+		WithContext(ctx).
+		With("storeName", storeName)
+
+	shop, err := entity.ParseShopDomain(storeName)
+	if err != nil {
+		logger.Info("invalid shop domain")
+		return nil, err
+	}
+
+	var responseBody accessTokenResponse
+	res, err := resty.New().R().
+		SetContext(ctx).
+		SetFormData(map[string]string{
+			"client_id":     s.app.APIKey,
+			"client_secret": s.app.Secret(),
+			"grant_type":    "refresh_token",
+			"refresh_token": refreshToken,
+		}).
+		SetResult(&responseBody).
+		Post(shop.URL("/admin/oauth/access_token"))
+	if err != nil {
+		logger.Error("failed to refresh access token", "err", err)
+		return nil, fmt.Errorf("failed to refresh access token: %w", err)
+	}
+	if res.StatusCode() != http.StatusOK {
+		logger.Error("failed to refresh access token", "status", res.StatusCode(), "resBody", res.String())
+		return nil, fmt.Errorf("failed to refresh access token: http status %d, body %s", res.StatusCode(), res.String())
+	}
+	if responseBody.AccessToken == "" {
+		logger.Error("refresh response doesn't contain access token")
+		return nil, fmt.Errorf("refresh response doesn't contain access token")
+	}
+
+	logger.Info("refreshed access token", "expiresAt", responseBody.expiresAt())
+	return &service.APIRefreshAccessTokenOutput{
+		AccessToken:           responseBody.AccessToken,
+		Scopes:                entity.ParseAccessScopes(responseBody.Scope).String(),
+		ExpiresAt:             responseBody.expiresAt(),
+		RefreshToken:          responseBody.RefreshToken,
+		RefreshTokenExpiresAt: responseBody.refreshTokenExpiresAt(),
+	}, nil
+}
+
+func (s *shopifyAPI) WithTokenSource(ctx context.Context, storeName string, source service.TokenSource) service.PlatformAPI {
+	h := s.newStoreClient(storeName)
+	h.SetTransport(&tokenTransport{
+		source: source,
+		base:   h.GetClient().Transport,
+	})
+
+	return s.withStoreClient(storeName, h)
+}
+
+// tokenTransport authenticates requests with access token provided by token source.
+// Request rejected with 401 is retried once with refreshed access token.
+type tokenTransport struct {
+	source service.TokenSource
+	base   http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	accessToken, err := t.source.Token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	res, err := t.do(req, accessToken)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	// Request can be sent again only if its body can be read again
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
+	}
+
+	refreshedToken, err := t.source.Refresh(req.Context(), accessToken)
+	if err != nil || refreshedToken == accessToken {
+		// Caller gets the original response
+		return res, nil
+	}
+
+	// Body of the original response is not needed anymore
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body again: %w", err)
+		}
+	}
+	return t.do(retry, refreshedToken)
+}
+
+// do sends request with access token without modifying the original request.
+func (t *tokenTransport) do(req *http.Request, accessToken string) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Shopify-Access-Token", accessToken)
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(req)
+}
+
+const accessScopesQuery = `
+query accessScopes {
+  currentAppInstallation {
+    accessScopes {
+      handle
+    }
+  }
+}`
+
+type accessScopesData struct {
+	CurrentAppInstallation struct {
+		AccessScopes []struct {
+			Handle string `json:"handle"`
+		} `json:"accessScopes"`
+	} `json:"currentAppInstallation"`
+}
+
+func (s *shopifyAPI) GetAccessScopes(ctx context.Context) (string, error) {
+	logger := s.logger.
+		Named("GetAccessScopes").
+		WithContext(ctx)
+
+	res, err := doGraphQL[accessScopesData](ctx, s, accessScopesQuery, nil)
+	if err != nil {
+		logger.Error("failed to get access scopes", "err", err)
+		return "", fmt.Errorf("failed to get access scopes: %w", err)
+	}
+
+	handles := make([]string, 0, len(res.Data.CurrentAppInstallation.AccessScopes))
+	for _, scope := range res.Data.CurrentAppInstallation.AccessScopes {
+		handles = append(handles, scope.Handle)
+	}
+	return entity.ParseAccessScopes(strings.Join(handles, ",")).String(), nil
+}
@@ -0,0 +1,86 @@
+package shopify
+
+import (
+	"sync"
+
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	headerShopifyAPIVersion          = "X-Shopify-API-Version"
+	headerShopifyAPIDeprecatedReason = "X-Shopify-API-Deprecated-Reason"
+)
+
+// graphQLPath returns path of GraphQL Admin API endpoint in the configured API version.
+func (s *shopifyAPI) graphQLPath() string {
+	return "/admin/api/" + s.cfg.Shopify.ApiVersion + "/graphql.json"
+}
+
+// restPath returns path of REST Admin API resource in the configured API version, e.g. orders.
+func (s *shopifyAPI) restPath(resource string) string {
+	return "/admin/api/" + s.cfg.Shopify.ApiVersion + "/" + resource + ".json"
+}
+
+// deprecation is a deprecated use of Admin API, counted per endpoint and reason.
+type deprecation struct {
+	Path   string
+	Reason string
+}
+
+// deprecations counts deprecated uses of Admin API reported by Shopify,
+// so they can be migrated before the API version they still work in is removed.
+type deprecations struct {
+	logger logging.Logger
+
+	mu     sync.Mutex
+	counts map[deprecation]int
+}
+
+func newDeprecations(logger logging.Logger) *deprecations {
+	return &deprecations{
+		logger: logger.Named("deprecations"),
+		counts: make(map[deprecation]int),
+	}
+}
+
+// add counts deprecated use of Admin API and logs it.
+// Deprecated calls are usually repeated, so every one of them is counted but only some are logged as warnings.
+func (d *deprecations) add(logger logging.Logger, dep deprecation, message string, args ...any) {
+	d.mu.Lock()
+	d.counts[dep]++
+	count := d.counts[dep]
+	d.mu.Unlock()
+
+	args = append(args, "reason", dep.Reason, "count", count)
+	if count == 1 || count%100 == 0 {
+		logger.Warn(message, args...)
+		return
+	}
+	logger.Debug(message, args...)
+}
+
+// checkAPIVersion is called after every response of Admin API.
+// It logs and counts requests Shopify reports as deprecated, and requests served by another API version
+// than the configured one, which happens when the configured version is not supported anymore.
+func (s *shopifyAPI) checkAPIVersion(_ *resty.Client, res *resty.Response) error {
+	path := res.Request.RawRequest.URL.Path
+	logger := s.deprecations.logger.
+		WithContext(res.Request.Context()).
+		With("path", path, "apiVersion", s.cfg.Shopify.ApiVersion)
+
+	servedVersion := res.Header().Get(headerShopifyAPIVersion)
+	if servedVersion != "" && servedVersion != s.cfg.Shopify.ApiVersion {
+		s.deprecations.add(logger, deprecation{
+			Path:   path,
+			Reason: "api version " + s.cfg.Shopify.ApiVersion + " is not supported",
+		}, "request is served by another api version", "servedVersion", servedVersion)
+	}
+
+	reason := res.Header().Get(headerShopifyAPIDeprecatedReason)
+	if reason != "" {
+		s.deprecations.add(logger, deprecation{Path: path, Reason: reason}, "deprecated api call")
+	}
+
+	return nil
+}
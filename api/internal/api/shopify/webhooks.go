@@ -1,46 +1,218 @@
 package shopify
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+	"slices"
 
-	"github.com/antflydb/shopify-app-template-go/internal/service"
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
 )
 
-type subscribeToWebhookRequestBody struct {
-	Address string `json:"address"`
-	Topic   string `json:"topic"`
-	Format  string `json:"format"`
+// webhookSubscriptionFields are selected for every webhook subscription.
+const webhookSubscriptionFields = `
+id
+topic
+endpoint {
+  __typename
+  ... on WebhookHttpEndpoint {
+    callbackUrl
+  }
+}`
+
+type webhookSubscription struct {
+	ID       string `json:"id"`
+	Topic    string `json:"topic"`
+	Endpoint struct {
+		TypeName    string `json:"__typename"`
+		CallbackURL string `json:"callbackUrl"`
+	} `json:"endpoint"`
+}
+
+func (w webhookSubscription) toEntity() entity.WebhookSubscription {
+	return entity.WebhookSubscription{
+		ID:      w.ID,
+		Topic:   w.Topic,
+		Address: w.Endpoint.CallbackURL,
+	}
+}
+
+const webhookSubscriptionsQuery = `
+query webhookSubscriptions($first: Int!, $after: String) {
+  webhookSubscriptions(first: $first, after: $after) {
+    nodes {` + webhookSubscriptionFields + `
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}`
+
+type webhookSubscriptionsData struct {
+	WebhookSubscriptions connection[webhookSubscription] `json:"webhookSubscriptions"`
 }
 
-func (s *shopifyAPI) SubscribeToAppUninstallWebhook(opts service.SubscribeToAppUninstallWebhookOptions) error {
+func (s *shopifyAPI) ListWebhookSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error) {
 	logger := s.logger.
-		Named("SubscribeToAppUninstallWebhook").
-		With("opts", opts)
-
-	var res, err = s.client.R().
-		SetBody(map[string]any{
-			"webhook": subscribeToWebhookRequestBody{
-				Address: opts.RedirectURL,
-				Topic:   "app/uninstalled",
-				Format:  "json",
-			},
-		}).
-		SetHeaders(map[string]string{
-			"Content-Type":           "application/json",
-			"X-Shopify-Access-Token": opts.AccessToken,
-		}).
-		Post(fmt.Sprintf("https://%s/admin/api/2022-04/webhooks.json", opts.StoreName))
+		Named("ListWebhookSubscriptions").
+		WithContext(ctx)
+
+	var subscriptions []entity.WebhookSubscription
+	pages := paginateGraphQL(ctx, s, webhookSubscriptionsQuery, nil, 0, func(data *webhookSubscriptionsData) *connection[webhookSubscription] {
+		return &data.WebhookSubscriptions
+	})
+	for w, err := range pages {
+		if err != nil {
+			logger.Error("failed to list webhook subscriptions", "err", err)
+			return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+		}
+		subscriptions = append(subscriptions, w.toEntity())
+	}
+
+	return subscriptions, nil
+}
+
+const webhookSubscriptionCreateMutation = `
+mutation webhookSubscriptionCreate($topic: WebhookSubscriptionTopic!, $webhookSubscription: WebhookSubscriptionInput!) {
+  webhookSubscriptionCreate(topic: $topic, webhookSubscription: $webhookSubscription) {
+    webhookSubscription {` + webhookSubscriptionFields + `
+    }
+    userErrors {
+      field
+      message
+    }
+  }
+}`
+
+type webhookSubscriptionInput struct {
+	CallbackURL string `json:"callbackUrl"`
+	Format      string `json:"format,omitempty"`
+}
+
+type webhookSubscriptionCreateData struct {
+	WebhookSubscriptionCreate struct {
+		WebhookSubscription *webhookSubscription `json:"webhookSubscription"`
+		userErrors
+	} `json:"webhookSubscriptionCreate"`
+}
+
+func (s *shopifyAPI) CreateWebhookSubscription(ctx context.Context, topic, address string) (*entity.WebhookSubscription, error) {
+	logger := s.logger.
+		Named("CreateWebhookSubscription").
+		WithContext(ctx).
+		With("topic", topic, "address", address)
+
+	res, err := doGraphQL[webhookSubscriptionCreateData](ctx, s, webhookSubscriptionCreateMutation, map[string]any{
+		"topic": entity.WebhookTopicEnum(topic),
+		"webhookSubscription": webhookSubscriptionInput{
+			CallbackURL: address,
+			Format:      "JSON",
+		},
+	})
+	if err != nil {
+		logger.Error("failed to create webhook subscription", "err", err)
+		return nil, fmt.Errorf("failed to create %s webhook subscription: %w", topic, err)
+	}
+	payload := res.Data.WebhookSubscriptionCreate
+	err = payload.err()
+	if err != nil {
+		logger.Error("webhook subscription is rejected", "err", err)
+		return nil, fmt.Errorf("failed to create %s webhook subscription: %w", topic, err)
+	}
+	if payload.WebhookSubscription == nil {
+		logger.Error("response doesn't contain created webhook subscription")
+		return nil, fmt.Errorf("failed to create %s webhook subscription: response doesn't contain it", topic)
+	}
+
+	subscription := payload.WebhookSubscription.toEntity()
+	logger.Info("created webhook subscription", "subscriptionID", subscription.ID)
+	return &subscription, nil
+}
+
+const webhookSubscriptionUpdateMutation = `
+mutation webhookSubscriptionUpdate($id: ID!, $webhookSubscription: WebhookSubscriptionInput!) {
+  webhookSubscriptionUpdate(id: $id, webhookSubscription: $webhookSubscription) {
+    webhookSubscription {
+      id
+    }
+    userErrors {
+      field
+      message
+    }
+  }
+}`
+
+type webhookSubscriptionUpdateData struct {
+	WebhookSubscriptionUpdate struct {
+		userErrors
+	} `json:"webhookSubscriptionUpdate"`
+}
+
+func (s *shopifyAPI) UpdateWebhookSubscription(ctx context.Context, subscriptionID, address string) error {
+	logger := s.logger.
+		Named("UpdateWebhookSubscription").
+		WithContext(ctx).
+		With("subscriptionID", subscriptionID, "address", address)
+
+	res, err := doGraphQL[webhookSubscriptionUpdateData](ctx, s, webhookSubscriptionUpdateMutation, map[string]any{
+		"id": subscriptionID,
+		"webhookSubscription": webhookSubscriptionInput{
+			CallbackURL: address,
+		},
+	})
+	if err != nil {
+		logger.Error("failed to update webhook subscription", "err", err)
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	err = res.Data.WebhookSubscriptionUpdate.err()
+	if err != nil {
+		logger.Error("webhook subscription update is rejected", "err", err)
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	logger.Info("updated webhook subscription")
+	return nil
+}
+
+const webhookSubscriptionDeleteMutation = `
+mutation webhookSubscriptionDelete($id: ID!) {
+  webhookSubscriptionDelete(id: $id) {
+    deletedWebhookSubscriptionId
+    userErrors {
+      field
+      message
+    }
+  }
+}`
+
+type webhookSubscriptionDeleteData struct {
+	WebhookSubscriptionDelete struct {
+		DeletedWebhookSubscriptionID *string `json:"deletedWebhookSubscriptionId"`
+		userErrors
+	} `json:"webhookSubscriptionDelete"`
+}
+
+func (s *shopifyAPI) DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error {
+	logger := s.logger.
+		Named("DeleteWebhookSubscription").
+		WithContext(ctx).
+		With("subscriptionID", subscriptionID)
+
+	res, err := doGraphQL[webhookSubscriptionDeleteData](ctx, s, webhookSubscriptionDeleteMutation, map[string]any{
+		"id": subscriptionID,
+	})
 	if err != nil {
-		logger.Error("failed to subscribe to shopify app/uninstalled webhook", "err", err)
-		return fmt.Errorf("failed to subscribe to shopify app/uninstalled webhook: %w", err)
+		logger.Error("failed to delete webhook subscription", "err", err)
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
 	}
-	if res.StatusCode() != http.StatusCreated {
-		logger.Error("failed to subscribe to shopify app/uninstalled webhook", "resBody", res.String())
-		return fmt.Errorf("failed to subscribe to shopify app/uninstalled webhook: http status %d, body %s", res.StatusCode(), res.String())
+	payload := res.Data.WebhookSubscriptionDelete
+	// Subscription could be already deleted, then its id is rejected
+	if len(payload.UserErrors) > 0 && !slices.Equal(payload.UserErrors[0].Field, []string{"id"}) {
+		err = payload.err()
+		logger.Error("webhook subscription deletion is rejected", "err", err)
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
 	}
-	logger = logger.With("resBody", res.Body())
 
-	logger.Info("successfully subscribed to shopify app/uninstalled webhook")
+	logger.Info("deleted webhook subscription")
 	return nil
 }
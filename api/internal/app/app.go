@@ -13,9 +13,11 @@ import (
 	"github.com/antflydb/shopify-app-template-go/config"
 	"github.com/antflydb/shopify-app-template-go/internal/api/shopify"
 	httpcontroller "github.com/antflydb/shopify-app-template-go/internal/controller/http"
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/internal/service"
 	"github.com/antflydb/shopify-app-template-go/internal/storage"
 	"github.com/antflydb/shopify-app-template-go/pkg/database"
+	"github.com/antflydb/shopify-app-template-go/pkg/encryption"
 	"github.com/antflydb/shopify-app-template-go/pkg/httpserver"
 	"github.com/antflydb/shopify-app-template-go/pkg/logging"
 	"github.com/golang-migrate/migrate/v4"
@@ -27,7 +29,8 @@ import (
 func Run(cfg *config.Config) {
 	logger := logging.NewZap(cfg.Log.Level)
 	logger.Info("loaded configuration", "apiKey", cfg.Shopify.ApiKey, "apiKeyLength", len(cfg.Shopify.ApiKey), "baseURL", cfg.App.BaseURL)
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Init db
 	sql, err := database.NewDatabase(ctx, cfg)
@@ -41,13 +44,34 @@ func Run(cfg *config.Config) {
 		logger.Fatal("migration failed", "err", err)
 	}
 
+	keyring, err := encryption.NewKeyring(cfg.Encryption.Keys)
+	if err != nil {
+		logger.Fatal("failed to create encryption keyring", "err", err)
+	}
+
 	storages := service.Storages{
-		Store: storage.NewStoreStorage(sql),
+		Store:             storage.NewStoreStorage(sql, keyring),
+		Session:           storage.NewSessionStorage(sql, keyring),
+		ComplianceRequest: storage.NewComplianceRequestStorage(sql),
+		ProcessedWebhook:  storage.NewProcessedWebhookStorage(sql),
+		WebhookJob:        storage.NewWebhookJobStorage(sql),
+	}
+
+	apps, err := entity.NewApps(&entity.App{
+		APIKey:  cfg.Shopify.ApiKey,
+		Secrets: []string{cfg.Shopify.ApiSecret, cfg.Shopify.ApiSecretPrevious},
+	}, cfg.Shopify.Apps)
+	if err != nil {
+		logger.Fatal("failed to configure apps", "err", err)
+	}
+	for _, app := range apps.All() {
+		logger.Info("serving app", "app", app.Handle, "apiKey", app.APIKey, "secrets", len(app.Secrets))
 	}
 
 	apis := service.APIs{
 		Platform: shopify.NewAPI(shopify.Options{
 			Config: cfg,
+			Apps:   apps,
 			Logger: logger,
 		}),
 	}
@@ -55,14 +79,31 @@ func Run(cfg *config.Config) {
 	serviceOptions := &service.Options{
 		Apis:     apis,
 		Storages: storages,
+		Webhooks: service.NewWebhookRouter(),
+		Apps:     apps,
 		Config:   cfg,
 		Logger:   logger,
 	}
 
 	services := service.Services{
 		Platform: service.NewPlatformService(serviceOptions),
+		Webhook:  service.NewWebhookService(serviceOptions),
 	}
 
+	// Run background jobs
+	webhookWorkers := service.NewWebhookWorkerPool(serviceOptions)
+	webhookWorkers.Start(ctx)
+
+	go runPeriodically(ctx, cfg.Webhooks.CleanupInterval, func(ctx context.Context) {
+		_ = services.Webhook.PurgeProcessedWebhooks(ctx)
+	})
+	go runPeriodically(ctx, cfg.Webhooks.ReconcileInterval, func(ctx context.Context) {
+		err := services.Platform.ReconcileAllWebhookSubscriptions(ctx)
+		if err != nil {
+			logger.Error("failed to reconcile webhook subscriptions", "err", err)
+		}
+	})
+
 	// Init native HTTP handler
 	mux := http.NewServeMux()
 
@@ -70,6 +111,7 @@ func Run(cfg *config.Config) {
 		Handler:  mux,
 		Services: services,
 		Storages: storages,
+		Apps:     apps,
 		Logger:   logger,
 		Config:   cfg,
 	})
@@ -100,6 +142,10 @@ func Run(cfg *config.Config) {
 		logger.Error("app - Run - httpServer.Shutdown", "err", err)
 	}
 
+	// Stop background jobs and wait for webhooks in progress
+	cancel()
+	webhookWorkers.Shutdown()
+
 	// Close database connection
 	sql.Close()
 }
@@ -137,3 +183,18 @@ func runMigrations(cfg *config.Config, logger logging.Logger) error {
 	logger.Info("migrations completed successfully")
 	return nil
 }
+
+// runPeriodically calls fn every interval until ctx is cancelled.
+func runPeriodically(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"context"
+
+	"github.com/antflydb/shopify-app-template-go/config"
+	"github.com/antflydb/shopify-app-template-go/internal/storage"
+	"github.com/antflydb/shopify-app-template-go/pkg/database"
+	"github.com/antflydb/shopify-app-template-go/pkg/encryption"
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+)
+
+// ReencryptAccessTokens encrypts all stored access tokens of stores and sessions with the active encryption key.
+// It's run after a new key is added to ENCRYPTION_KEYS, so the old key can be removed afterwards.
+func ReencryptAccessTokens(cfg *config.Config) {
+	logger := logging.NewZap(cfg.Log.Level)
+	ctx := context.Background()
+
+	sql, err := database.NewDatabase(ctx, cfg)
+	if err != nil {
+		logger.Fatal("failed to connect to database", "err", err)
+	}
+	defer sql.Close()
+
+	keyring, err := encryption.NewKeyring(cfg.Encryption.Keys)
+	if err != nil {
+		logger.Fatal("failed to create encryption keyring", "err", err)
+	}
+
+	reencrypted, err := storage.NewStoreStorage(sql, keyring).ReencryptAccessTokens(ctx)
+	if err != nil {
+		logger.Fatal("failed to re-encrypt access tokens", "err", err, "reencrypted", reencrypted)
+	}
+
+	reencryptedSessions, err := storage.NewSessionStorage(sql, keyring).ReencryptAccessTokens(ctx)
+	if err != nil {
+		logger.Fatal("failed to re-encrypt session access tokens", "err", err,
+			"reencrypted", reencrypted, "reencryptedSessions", reencryptedSessions)
+	}
+
+	logger.Info("successfully re-encrypted access tokens",
+		"reencrypted", reencrypted, "reencryptedSessions", reencryptedSessions, "keyID", keyring.ActiveKeyID())
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/DataDog/gostackparse"
 	"github.com/antflydb/shopify-app-template-go/config"
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/internal/service"
 	"github.com/antflydb/shopify-app-template-go/pkg/logging"
 )
@@ -20,6 +21,7 @@ type Options struct {
 	Handler  *http.ServeMux
 	Services service.Services
 	Storages service.Storages
+	Apps     *entity.Apps
 	Logger   logging.Logger
 	Config   *config.Config
 }
@@ -29,6 +31,7 @@ type RouterOptions struct {
 	Handler  *http.ServeMux
 	Services service.Services
 	Storages service.Storages
+	Apps     *entity.Apps
 	Logger   logging.Logger
 	Config   *config.Config
 }
@@ -46,6 +49,7 @@ func New(options *Options) {
 		Handler:  options.Handler,
 		Services: options.Services,
 		Storages: options.Storages,
+		Apps:     options.Apps,
 		Logger:   options.Logger.Named("HTTPController"),
 		Config:   options.Config,
 	}
@@ -55,9 +59,31 @@ func New(options *Options) {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	// Routes of additional apps are served under their prefix, e.g. /apps/staging/auth/callback
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		options.Handler.Handle(method+" /apps/{app}/", appPrefixHandler(routerOptions))
+	}
+
 	// Routers
 	{
 		newPlatformRoutes(routerOptions)
+		newWebhookRoutes(routerOptions)
+	}
+}
+
+// appPrefixHandler resolves app from the route prefix, puts it into request context
+// and serves the request with the prefix stripped, so all apps share the same routes.
+func appPrefixHandler(options RouterOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		app := options.Apps.ByHandle(r.PathValue("app"))
+		// Default app is served without prefix, and prefixes can't be nested
+		if app == nil || app.Handle == entity.DefaultAppHandle || service.AppFromContext(r.Context()) != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		ctx := service.ContextWithApp(r.Context(), app)
+		http.StripPrefix(app.PathPrefix(), options.Handler).ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
@@ -93,6 +119,7 @@ type RequestContext struct {
 	Config   *config.Config
 	Services service.Services
 	Storages service.Storages
+	Apps     *entity.Apps
 	ctx      context.Context
 }
 
@@ -106,6 +133,15 @@ func (r *RequestContext) WithContext(ctx context.Context) {
 	r.ctx = ctx
 }
 
+// App returns app the request is made to, resolved from the route prefix.
+// Requests without prefix are made to the default app.
+func (r *RequestContext) App() *entity.App {
+	if app := service.AppFromContext(r.ctx); app != nil {
+		return app
+	}
+	return r.Apps.Default()
+}
+
 // JSON writes JSON response
 func (r *RequestContext) JSON(status int, data any) error {
 	r.Writer.Header().Set("Content-Type", "application/json")
@@ -150,6 +186,7 @@ func wrapHandler(options RouterOptions, handler func(r *RequestContext) (any, *h
 			Config:   options.Config,
 			Services: options.Services,
 			Storages: options.Storages,
+			Apps:     options.Apps,
 			ctx:      r.Context(),
 		}
 
@@ -178,7 +215,11 @@ func wrapHandler(options RouterOptions, handler func(r *RequestContext) (any, *h
 				}
 			} else {
 				logger.Info("client error")
-				reqCtx.JSON(http.StatusUnprocessableEntity, err)
+				code := http.StatusUnprocessableEntity
+				if err.Code != 0 {
+					code = err.Code
+				}
+				reqCtx.JSON(code, err)
 			}
 			return
 		}
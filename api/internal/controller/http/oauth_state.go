@@ -0,0 +1,91 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+// oauthStateCookieName is the name of the cookie binding OAuth state to user's browser.
+const oauthStateCookieName = "shopify_app_state"
+
+// oauthStateCookiePath limits the cookie to the OAuth callback of the app.
+func oauthStateCookiePath(app *entity.App) string {
+	return app.PathPrefix() + "/auth/callback"
+}
+
+// setOAuthStateCookie sets signed cookie containing OAuth state nonce.
+// The cookie is sent back only by the browser which started authorization,
+// so the callback can't be replayed from another browser.
+func (r *platformRoutes) setOAuthStateCookie(c *RequestContext, nonce string) {
+	app := c.App()
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookieName,
+		Value:    nonce + "." + signOAuthState(nonce, app.Secret()),
+		Path:     oauthStateCookiePath(app),
+		MaxAge:   int(r.cfg.Shopify.OAuthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		// App is opened inside Shopify admin iframe, so the cookie has to be sent in cross-site requests
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+// clearOAuthStateCookie removes OAuth state cookie.
+func (r *platformRoutes) clearOAuthStateCookie(c *RequestContext) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookieName,
+		Value:    "",
+		Path:     oauthStateCookiePath(c.App()),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+// verifyOAuthStateCookie verifies cookie signature and that the cookie contains the given state.
+// Cookie set before the app's secret was rotated is signed with the previous secret.
+func (r *platformRoutes) verifyOAuthStateCookie(c *RequestContext, state string) error {
+	if state == "" {
+		return errors.New("missing state parameter")
+	}
+
+	cookie, err := c.Request.Cookie(oauthStateCookieName)
+	if err != nil {
+		return errors.New("missing state cookie")
+	}
+
+	nonce, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || nonce == "" {
+		return errors.New("malformed state cookie")
+	}
+	verified := false
+	for _, secret := range c.App().Secrets {
+		if hmac.Equal([]byte(signature), []byte(signOAuthState(nonce, secret))) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return errors.New("invalid state cookie signature")
+	}
+	if subtle.ConstantTimeCompare([]byte(nonce), []byte(state)) != 1 {
+		return errors.New("state doesn't match state cookie")
+	}
+
+	return nil
+}
+
+// signOAuthState returns base64 encoded HMAC-SHA256 of the nonce.
+func signOAuthState(nonce, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -1,42 +1,71 @@
 package http
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/internal/service"
 	"github.com/antflydb/shopify-app-template-go/pkg/errs"
 )
 
+const (
+	headerShopifyReauthorize    = "X-Shopify-API-Request-Failure-Reauthorize"
+	headerShopifyReauthorizeURL = "X-Shopify-API-Request-Failure-Reauthorize-Url"
+)
+
 type platformRoutes struct {
 	RouterContext
 }
 
+// reauthorizeErr returns 401 error with headers telling the app's frontend to send user
+// through authorization again, if err is a service.ReauthorizeRequiredError.
+func reauthorizeErr(c *RequestContext, err error) (*httpErr, bool) {
+	var target *service.ReauthorizeRequiredError
+	if !errors.As(err, &target) {
+		return nil, false
+	}
+
+	c.Writer.Header().Set(headerShopifyReauthorize, "1")
+	c.Writer.Header().Set(headerShopifyReauthorizeURL, target.RedirectURL)
+	return &httpErr{Type: ErrorTypeClient, Code: http.StatusUnauthorized, Message: target.Error()}, true
+}
+
 // bindQuery binds query parameters to a struct
 func bindQuery(values url.Values, target any) error {
 	// Simple implementation - in production you might want to use a library like gorilla/schema
 	switch v := target.(type) {
 	case *handlerRequestQuery:
-		v.StoreName = values.Get("shop")
-		if v.StoreName == "" {
-			return fmt.Errorf("required parameter 'shop' is missing")
+		storeName, err := bindShopDomain(values)
+		if err != nil {
+			return err
 		}
+		v.StoreName = storeName
 	case *redirectHandlerRequestQuery:
-		v.StoreName = values.Get("shop")
-		if v.StoreName == "" {
-			return fmt.Errorf("required parameter 'shop' is missing")
-		}
-	case *uninstallHandlerRequestQuery:
-		v.StoreName = values.Get("shop")
-		if v.StoreName == "" {
-			return fmt.Errorf("required parameter 'shop' is missing")
+		storeName, err := bindShopDomain(values)
+		if err != nil {
+			return err
 		}
+		v.StoreName = storeName
 	}
 	return nil
 }
 
+// bindShopDomain validates and returns 'shop' query parameter.
+func bindShopDomain(values url.Values) (entity.ShopDomain, error) {
+	shop := values.Get("shop")
+	if shop == "" {
+		return "", fmt.Errorf("required parameter 'shop' is missing")
+	}
+	storeName, err := entity.ParseShopDomain(shop)
+	if err != nil {
+		return "", fmt.Errorf("parameter 'shop' is invalid: %w", err)
+	}
+	return storeName, nil
+}
+
 func newPlatformRoutes(options RouterOptions) {
 	r := &platformRoutes{RouterContext{
 		services: options.Services,
@@ -47,13 +76,16 @@ func newPlatformRoutes(options RouterOptions) {
 
 	options.Handler.HandleFunc("GET /", wrapHandler(options, r.handler))
 	options.Handler.HandleFunc("GET /auth/callback", wrapHandler(options, r.redirectHandler))
-	options.Handler.HandleFunc("POST /uninstall", wrapHandler(options, r.uninstallHandler))
-	options.Handler.HandleFunc("GET /api/products/count", wrapHandler(options, r.getProductsCount))
-	options.Handler.HandleFunc("GET /api/products/create", wrapHandler(options, r.createProducts))
+	options.Handler.HandleFunc("GET /api/products", wrapHandler(options, verifySessionToken(r.listProducts)))
+	options.Handler.HandleFunc("POST /api/products", wrapHandler(options, verifySessionToken(r.createProduct)))
+	options.Handler.HandleFunc("GET /api/products/count", wrapHandler(options, verifySessionToken(r.getProductsCount)))
+	options.Handler.HandleFunc("GET /api/products/{id}", wrapHandler(options, verifySessionToken(r.getProduct)))
+	options.Handler.HandleFunc("PUT /api/products/{id}", wrapHandler(options, verifySessionToken(r.updateProduct)))
+	options.Handler.HandleFunc("DELETE /api/products/{id}", wrapHandler(options, verifySessionToken(r.deleteProduct)))
 }
 
 type handlerRequestQuery struct {
-	StoreName string `form:"shop" binding:"required"`
+	StoreName entity.ShopDomain `form:"shop" binding:"required"`
 }
 
 func (r *platformRoutes) handler(c *RequestContext) (any, *httpErr) {
@@ -67,7 +99,7 @@ func (r *platformRoutes) handler(c *RequestContext) (any, *httpErr) {
 	}
 	logger = logger.With("requestQuery", requestQuery)
 
-	redirectURL, err := r.services.Platform.Handle(c.Context(), requestQuery.StoreName, c.Request.URL.String())
+	output, err := r.services.Platform.Handle(c.Context(), requestQuery.StoreName.String(), c.Request.URL.String())
 	if err != nil {
 		if errs.IsExpected(err) {
 			logger.Info(err.Error())
@@ -80,16 +112,21 @@ func (r *platformRoutes) handler(c *RequestContext) (any, *httpErr) {
 			Details: err,
 		}
 	}
-	logger = logger.With("redirectURL", redirectURL)
+	logger = logger.With("redirectURL", output.RedirectURL)
 
-	c.Redirect(http.StatusFound, redirectURL)
+	// Bind started authorization to user's browser
+	if output.Nonce != "" {
+		r.setOAuthStateCookie(c, output.Nonce)
+	}
+
+	c.Redirect(http.StatusFound, output.RedirectURL)
 
 	logger.Info("successfully handled call")
 	return nil, nil
 }
 
 type redirectHandlerRequestQuery struct {
-	StoreName string `form:"shop" binding:"required"`
+	StoreName entity.ShopDomain `form:"shop" binding:"required"`
 }
 
 func (r *platformRoutes) redirectHandler(c *RequestContext) (any, *httpErr) {
@@ -103,8 +140,15 @@ func (r *platformRoutes) redirectHandler(c *RequestContext) (any, *httpErr) {
 	}
 	logger = logger.With("requestQuery", requestQuery)
 
+	err = r.verifyOAuthStateCookie(c, c.Request.URL.Query().Get("state"))
+	r.clearOAuthStateCookie(c)
+	if err != nil {
+		logger.Info("failed to verify oauth state", "err", err)
+		return nil, &httpErr{Type: ErrorTypeClient, Code: http.StatusForbidden, Message: "invalid oauth state"}
+	}
+
 	err = r.services.Platform.HandleRedirect(c.Context(), service.ServiceHandleRedirectOptions{
-		StoreName:     requestQuery.StoreName,
+		StoreName:     requestQuery.StoreName.String(),
 		RedirectedURL: c.Request.URL.String(),
 	})
 	if err != nil {
@@ -120,100 +164,11 @@ func (r *platformRoutes) redirectHandler(c *RequestContext) (any, *httpErr) {
 		}
 	}
 	// After successful handling of redirect call, redirect user to app's UI at their platform store
-	redirectURL := fmt.Sprintf("https://%s/admin/apps/%s", requestQuery.StoreName, r.cfg.Shopify.ApiKey)
-	logger.Info("redirecting to app UI", "redirectURL", redirectURL, "apiKey", r.cfg.Shopify.ApiKey)
+	app := c.App()
+	redirectURL := requestQuery.StoreName.URL("/admin/apps/" + app.APIKey)
+	logger.Info("redirecting to app UI", "redirectURL", redirectURL, "app", app.Handle)
 	c.Redirect(http.StatusFound, redirectURL)
 
 	logger.Info("successfully handled redirect call")
 	return nil, nil
 }
-
-type uninstallHandlerRequestQuery struct {
-	StoreName string `form:"shop" binding:"required"`
-}
-
-func (r *platformRoutes) uninstallHandler(c *RequestContext) (any, *httpErr) {
-	logger := r.logger.
-		Named("uninstallHandler").
-		WithContext(c.Context())
-
-	var requestQuery uninstallHandlerRequestQuery
-	err := bindQuery(c.Request.URL.Query(), &requestQuery)
-	if err != nil {
-		logger.Info("failed to parse request query", "err", err)
-		return nil, &httpErr{Type: ErrorTypeClient, Message: "invalid request query", Details: err}
-	}
-	logger = logger.With("requestQuery", requestQuery)
-
-	err = r.services.Platform.HandleUninstall(c.Context(), requestQuery.StoreName)
-	if err != nil {
-		if errs.IsExpected(err) {
-			logger.Info(err.Error())
-			return nil, &httpErr{Type: ErrorTypeClient, Message: err.Error()}
-		}
-		logger.Error("failed to uninstall app", "err", err)
-		return nil, &httpErr{
-			Type:    ErrorTypeServer,
-			Message: "failed to failed to uninstall app",
-			Details: err,
-		}
-	}
-
-	logger.Info("successfully uninstalled the application")
-	return nil, nil
-}
-
-type getProductsCountResponse struct {
-	Count int `json:"count"`
-}
-
-func (r *platformRoutes) getProductsCount(c *RequestContext) (any, *httpErr) {
-	logger := r.logger.Named("getProductsCount")
-
-	// Set authorization in context - create a new context with the auth header
-	ctx := c.Context()
-	if auth := c.Request.Header.Get("Authorization"); auth != "" {
-		ctx = context.WithValue(ctx, "Authorization", auth)
-		c.WithContext(ctx)
-	}
-
-	count, err := r.services.Platform.GetProductsCount(c.Context())
-	if err != nil {
-		// TODO: return custom errors to client, instead of 500
-		logger.Error("failed to get products count", "err", err)
-		return nil, &httpErr{
-			Type:    ErrorTypeServer,
-			Message: "failed to get products count",
-			Details: err,
-		}
-	}
-	logger = logger.With("count", count)
-
-	logger.Info("successfully got products count")
-	return getProductsCountResponse{Count: count}, nil
-}
-
-func (r *platformRoutes) createProducts(c *RequestContext) (any, *httpErr) {
-	logger := r.logger.Named("createProducts")
-
-	// Set authorization in context - create a new context with the auth header
-	ctx := c.Context()
-	if auth := c.Request.Header.Get("Authorization"); auth != "" {
-		ctx = context.WithValue(ctx, "Authorization", auth)
-		c.WithContext(ctx)
-	}
-
-	err := r.services.Platform.CreateProducts(c.Context())
-	if err != nil {
-		// TODO: return custom errors to client, instead of 500
-		logger.Error("failed to create products", "err", err)
-		return nil, &httpErr{
-			Type:    ErrorTypeServer,
-			Message: "failed to create products",
-			Details: err,
-		}
-	}
-
-	logger.Info("successfully created products")
-	return "", nil
-}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/internal/service"
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+)
+
+// maxProductBodySize limits the size of product request bodies read into memory.
+const maxProductBodySize = 1 << 20
+
+// productIDRegexp matches numeric ID of a product, which is the last part of its GraphQL global ID.
+var productIDRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+// productID returns GraphQL global ID of the product from the route, e.g. gid://shopify/Product/1 for /api/products/1.
+func productID(c *RequestContext) (string, *httpErr) {
+	id := c.Request.PathValue("id")
+	if !productIDRegexp.MatchString(id) {
+		return "", &httpErr{Type: ErrorTypeClient, Code: http.StatusNotFound, Message: service.ErrProductNotFound.Error()}
+	}
+	return "gid://shopify/Product/" + id, nil
+}
+
+// bindProductInput decodes product from request body, unknown fields are rejected.
+func bindProductInput(c *RequestContext) (*service.ProductInput, *httpErr) {
+	decoder := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxProductBodySize))
+	decoder.DisallowUnknownFields()
+
+	var input service.ProductInput
+	err := decoder.Decode(&input)
+	if err != nil {
+		return nil, &httpErr{Type: ErrorTypeClient, Code: http.StatusBadRequest, Message: "invalid request body", Details: err.Error()}
+	}
+	return &input, nil
+}
+
+// productErr returns error of product request, expected errors are returned to the client
+// and the rest as internal server error with the message.
+func productErr(c *RequestContext, logger logging.Logger, err error, message string) *httpErr {
+	if reauthErr, ok := reauthorizeErr(c, err); ok {
+		logger.Info(err.Error())
+		return reauthErr
+	}
+	if errors.Is(err, service.ErrInsufficientScopes) {
+		logger.Info(err.Error())
+		return &httpErr{Type: ErrorTypeClient, Code: http.StatusForbidden, Message: err.Error()}
+	}
+	if errors.Is(err, service.ErrProductNotFound) {
+		logger.Info(err.Error())
+		return &httpErr{Type: ErrorTypeClient, Code: http.StatusNotFound, Message: err.Error()}
+	}
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		logger.Info(err.Error())
+		validationErrors := make(map[string]any, len(validationErr.Fields))
+		for field, reason := range validationErr.Fields {
+			validationErrors[field] = reason
+		}
+		return &httpErr{Type: ErrorTypeClient, Message: "invalid product", ValidationErrors: validationErrors}
+	}
+	var userErrs service.APIUserErrors
+	if errors.As(err, &userErrs) {
+		logger.Info(err.Error())
+		return &httpErr{Type: ErrorTypeClient, Message: "product is rejected", Details: userErrs}
+	}
+	logger.Error(message, "err", err)
+	return &httpErr{
+		Type:    ErrorTypeServer,
+		Message: message,
+		Details: err,
+	}
+}
+
+type getProductsCountResponse struct {
+	Count int `json:"count"`
+}
+
+func (r *platformRoutes) getProductsCount(c *RequestContext) (any, *httpErr) {
+	logger := r.logger.Named("getProductsCount")
+
+	count, err := r.services.Platform.GetProductsCount(c.Context())
+	if err != nil {
+		return nil, productErr(c, logger, err, "failed to get products count")
+	}
+	logger = logger.With("count", count)
+
+	logger.Info("successfully got products count")
+	return getProductsCountResponse{Count: count}, nil
+}
+
+type listProductsResponse struct {
+	Products []entity.Product `json:"products"`
+}
+
+func (r *platformRoutes) listProducts(c *RequestContext) (any, *httpErr) {
+	logger := r.logger.Named("listProducts")
+
+	query := c.Request.URL.Query()
+	opts := service.ListProductsOptions{Query: query.Get("query")}
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 {
+			logger.Info("invalid limit", "limit", rawLimit)
+			return nil, &httpErr{Type: ErrorTypeClient, Message: "invalid request query", Details: fmt.Sprintf("parameter 'limit' must be between 1 and %d", service.MaxProductsLimit)}
+		}
+		opts.Limit = limit
+	}
+	logger = logger.With("opts", opts)
+
+	products, err := r.services.Platform.ListProducts(c.Context(), opts)
+	if err != nil {
+		return nil, productErr(c, logger, err, "failed to list products")
+	}
+
+	logger.Info("successfully listed products", "count", len(products))
+	return listProductsResponse{Products: products}, nil
+}
+
+func (r *platformRoutes) getProduct(c *RequestContext) (any, *httpErr) {
+	logger := r.logger.Named("getProduct")
+
+	id, idErr := productID(c)
+	if idErr != nil {
+		return nil, idErr
+	}
+	logger = logger.With("productID", id)
+
+	product, err := r.services.Platform.GetProduct(c.Context(), id)
+	if err != nil {
+		return nil, productErr(c, logger, err, "failed to get product")
+	}
+
+	logger.Info("successfully got product")
+	return product, nil
+}
+
+func (r *platformRoutes) createProduct(c *RequestContext) (any, *httpErr) {
+	logger := r.logger.Named("createProduct")
+
+	input, bindErr := bindProductInput(c)
+	if bindErr != nil {
+		logger.Info("failed to parse request body", "err", bindErr.Details)
+		return nil, bindErr
+	}
+
+	product, err := r.services.Platform.CreateProduct(c.Context(), input)
+	if err != nil {
+		return nil, productErr(c, logger, err, "failed to create product")
+	}
+
+	logger.Info("successfully created product", "productID", product.ID)
+	return product, nil
+}
+
+func (r *platformRoutes) updateProduct(c *RequestContext) (any, *httpErr) {
+	logger := r.logger.Named("updateProduct")
+
+	id, idErr := productID(c)
+	if idErr != nil {
+		return nil, idErr
+	}
+	logger = logger.With("productID", id)
+
+	input, bindErr := bindProductInput(c)
+	if bindErr != nil {
+		logger.Info("failed to parse request body", "err", bindErr.Details)
+		return nil, bindErr
+	}
+
+	product, err := r.services.Platform.UpdateProduct(c.Context(), id, input)
+	if err != nil {
+		return nil, productErr(c, logger, err, "failed to update product")
+	}
+
+	logger.Info("successfully updated product")
+	return product, nil
+}
+
+type deleteProductResponse struct {
+	ID string `json:"id"`
+}
+
+func (r *platformRoutes) deleteProduct(c *RequestContext) (any, *httpErr) {
+	logger := r.logger.Named("deleteProduct")
+
+	id, idErr := productID(c)
+	if idErr != nil {
+		return nil, idErr
+	}
+	logger = logger.With("productID", id)
+
+	err := r.services.Platform.DeleteProduct(c.Context(), id)
+	if err != nil {
+		return nil, productErr(c, logger, err, "failed to delete product")
+	}
+
+	logger.Info("successfully deleted product")
+	return deleteProductResponse{ID: id}, nil
+}
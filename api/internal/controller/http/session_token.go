@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/antflydb/shopify-app-template-go/internal/service"
+	"github.com/antflydb/shopify-app-template-go/pkg/errs"
+)
+
+// headerShopifyRetryInvalidSessionRequest tells App Bridge to retry the request with a new session token.
+const headerShopifyRetryInvalidSessionRequest = "X-Shopify-Retry-Invalid-Session-Request"
+
+// verifySessionToken authenticates request made by the app's frontend with App Bridge session token
+// from the Authorization header, and puts the authenticated principal into request context.
+// Request with missing or invalid session token is rejected with 401, as well as request
+// made under route prefix of an app other than the one the session token is issued for.
+// https://shopify.dev/docs/apps/build/authentication-authorization/session-tokens/set-up-session-tokens
+func verifySessionToken(handler func(c *RequestContext) (any, *httpErr)) func(c *RequestContext) (any, *httpErr) {
+	return func(c *RequestContext) (any, *httpErr) {
+		logger := c.Logger.Named("verifySessionToken")
+
+		sessionToken, ok := bearerToken(c.Request.Header.Get("Authorization"))
+		if !ok {
+			logger.Info("missing session token")
+			return nil, invalidSessionErr(c)
+		}
+
+		principal, err := c.Services.Platform.VerifySessionToken(c.Context(), sessionToken)
+		if err != nil {
+			if errs.IsExpected(err) {
+				logger.Info(err.Error())
+				return nil, invalidSessionErr(c)
+			}
+			logger.Error("failed to verify session token", "err", err)
+			return nil, &httpErr{
+				Type:    ErrorTypeServer,
+				Message: "failed to verify session token",
+				Details: err,
+			}
+		}
+
+		if app := service.AppFromContext(c.Context()); app != nil && app.Handle != principal.App {
+			logger.Info("session token is issued for another app", "app", app.Handle, "tokenApp", principal.App)
+			return nil, invalidSessionErr(c)
+		}
+
+		c.WithContext(service.ContextWithPrincipal(c.Context(), principal))
+
+		return handler(c)
+	}
+}
+
+// invalidSessionErr returns 401 error with header telling App Bridge to retry the request with a new session token.
+func invalidSessionErr(c *RequestContext) *httpErr {
+	c.Writer.Header().Set(headerShopifyRetryInvalidSessionRequest, "1")
+	return &httpErr{Type: ErrorTypeClient, Code: http.StatusUnauthorized, Message: service.ErrInvalidSessionToken.Error()}
+}
+
+// bearerToken returns token of "Bearer <token>" authorization header.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
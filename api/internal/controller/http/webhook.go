@@ -0,0 +1,225 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/internal/service"
+	"github.com/antflydb/shopify-app-template-go/pkg/errs"
+)
+
+const (
+	// maxWebhookBodySize limits the size of webhook payloads read into memory.
+	maxWebhookBodySize = 1 << 20
+
+	headerShopifyHmac       = "X-Shopify-Hmac-Sha256"
+	headerShopifyShopDomain = "X-Shopify-Shop-Domain"
+	headerShopifyTopic      = "X-Shopify-Topic"
+	headerShopifyWebhookID  = "X-Shopify-Webhook-Id"
+	headerShopifyAPIVersion = "X-Shopify-API-Version"
+	headerShopifyAPIKey     = "X-Shopify-API-Key"
+)
+
+type webhookRoutes struct {
+	RouterContext
+}
+
+func newWebhookRoutes(options RouterOptions) {
+	r := &webhookRoutes{RouterContext{
+		services: options.Services,
+		storages: options.Storages,
+		logger:   options.Logger.Named("webhookRoutes"),
+		cfg:      options.Config,
+	}}
+
+	options.Handler.HandleFunc("POST /webhooks", wrapHandler(options, verifyWebhook(r.webhookHandler)))
+	// Kept for app/uninstalled subscriptions created before all topics were routed through /webhooks
+	options.Handler.HandleFunc("POST /uninstall", wrapHandler(options, verifyWebhook(r.webhookHandler)))
+	options.Handler.HandleFunc("POST /api/webhooks/reconcile", wrapHandler(options, verifySessionToken(r.reconcileHandler)))
+}
+
+func (r *webhookRoutes) webhookHandler(c *RequestContext) (any, *httpErr) {
+	logger := r.logger.
+		Named("webhookHandler").
+		WithContext(c.Context())
+
+	webhook := webhookFromContext(c.Context())
+	logger = logger.With("webhookID", webhook.ID, "topic", webhook.Topic, "app", webhook.App.Handle, "storeName", webhook.StoreName)
+
+	if webhook.Topic == "" {
+		logger.Info("missing webhook topic")
+		return nil, &httpErr{Type: ErrorTypeClient, Code: http.StatusBadRequest, Message: "missing webhook topic"}
+	}
+
+	err := r.services.Webhook.HandleWebhook(c.Context(), &service.Webhook{
+		ID:         webhook.ID,
+		Topic:      webhook.Topic,
+		App:        webhook.App.Handle,
+		StoreName:  webhook.StoreName,
+		APIVersion: webhook.APIVersion,
+		Payload:    webhook.Body,
+	})
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, &httpErr{Type: ErrorTypeClient, Message: err.Error()}
+		}
+		logger.Error("failed to handle webhook", "err", err)
+		return nil, &httpErr{
+			Type:    ErrorTypeServer,
+			Message: "failed to handle webhook",
+			Details: err,
+		}
+	}
+
+	logger.Info("successfully handled webhook")
+	return nil, nil
+}
+
+func (r *webhookRoutes) reconcileHandler(c *RequestContext) (any, *httpErr) {
+	logger := r.logger.Named("reconcileHandler")
+
+	output, err := r.services.Platform.ReconcileWebhookSubscriptions(c.Context())
+	if err != nil {
+		if reauthErr, ok := reauthorizeErr(c, err); ok {
+			logger.Info(err.Error())
+			return nil, reauthErr
+		}
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, &httpErr{Type: ErrorTypeClient, Message: err.Error()}
+		}
+		logger.Error("failed to reconcile webhook subscriptions", "err", err)
+		return nil, &httpErr{
+			Type:    ErrorTypeServer,
+			Message: "failed to reconcile webhook subscriptions",
+			Details: err,
+		}
+	}
+
+	logger.Info("successfully reconciled webhook subscriptions")
+	return output, nil
+}
+
+// webhookContextKey is used to store verified webhook in request context.
+type webhookContextKey struct{}
+
+// webhookRequest contains verified data of an incoming webhook.
+type webhookRequest struct {
+	ID         string
+	Topic      string
+	App        *entity.App
+	StoreName  string
+	APIVersion string
+	Body       []byte
+}
+
+// webhookFromContext returns verified webhook from context.
+func webhookFromContext(ctx context.Context) *webhookRequest {
+	webhook, _ := ctx.Value(webhookContextKey{}).(*webhookRequest)
+	return webhook
+}
+
+// verifyWebhook verifies that the incoming request is a webhook sent by Shopify.
+// It reads the raw body, checks the X-Shopify-Hmac-Sha256 header against secrets of the apps
+// the webhook can be delivered to and only then passes the request to the handler.
+// The app whose secret the webhook is signed with is the app it's delivered to.
+// https://shopify.dev/docs/apps/build/webhooks/subscribe/https#step-5-verify-the-webhook
+func verifyWebhook(handler func(c *RequestContext) (any, *httpErr)) func(c *RequestContext) (any, *httpErr) {
+	return func(c *RequestContext) (any, *httpErr) {
+		logger := c.Logger.Named("verifyWebhook")
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodySize))
+		if err != nil {
+			logger.Info("failed to read webhook body", "err", err)
+			return nil, &httpErr{Type: ErrorTypeClient, Code: http.StatusBadRequest, Message: "invalid webhook body"}
+		}
+
+		app, err := verifyWebhookApp(c, body)
+		if err != nil {
+			logger.Info("failed to verify webhook", "err", err)
+			return nil, &httpErr{Type: ErrorTypeClient, Code: http.StatusUnauthorized, Message: "unauthorized"}
+		}
+
+		storeName, err := entity.ParseShopDomain(c.Request.Header.Get(headerShopifyShopDomain))
+		if err != nil {
+			logger.Info("invalid shop domain header", "err", err)
+			return nil, &httpErr{Type: ErrorTypeClient, Code: http.StatusBadRequest, Message: "invalid shop domain"}
+		}
+
+		c.WithContext(context.WithValue(c.Context(), webhookContextKey{}, &webhookRequest{
+			ID:         c.Request.Header.Get(headerShopifyWebhookID),
+			Topic:      c.Request.Header.Get(headerShopifyTopic),
+			App:        app,
+			StoreName:  storeName.String(),
+			APIVersion: c.Request.Header.Get(headerShopifyAPIVersion),
+			Body:       body,
+		}))
+
+		return handler(c)
+	}
+}
+
+// verifyWebhookApp returns app the webhook is signed by.
+// Webhook is verified against the app with the API key header if it's present,
+// otherwise against the app of the route prefix, falling back to all apps.
+func verifyWebhookApp(c *RequestContext, body []byte) (*entity.App, error) {
+	var apps []*entity.App
+	if apiKey := c.Request.Header.Get(headerShopifyAPIKey); apiKey != "" {
+		app := c.Apps.ByAPIKey(apiKey)
+		if app == nil {
+			return nil, errors.New("unknown api key")
+		}
+		if prefixApp := service.AppFromContext(c.Context()); prefixApp != nil && prefixApp != app {
+			return nil, errors.New("api key doesn't match app of the route")
+		}
+		apps = []*entity.App{app}
+	} else if app := service.AppFromContext(c.Context()); app != nil {
+		apps = []*entity.App{app}
+	} else {
+		apps = c.Apps.All()
+	}
+
+	signature := c.Request.Header.Get(headerShopifyHmac)
+	err := errors.New("api secret is not configured")
+	for _, app := range apps {
+		// Webhooks sent before the secret was rotated are signed with the previous secret
+		for _, secret := range app.Secrets {
+			err = verifyWebhookHmac(body, signature, secret)
+			if err == nil {
+				return app, nil
+			}
+		}
+	}
+
+	return nil, err
+}
+
+// verifyWebhookHmac compares base64 encoded HMAC-SHA256 of the body with the provided one in constant time.
+func verifyWebhookHmac(body []byte, signature, secret string) error {
+	if signature == "" {
+		return errors.New("missing hmac header")
+	}
+	if secret == "" {
+		return errors.New("api secret is not configured")
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("malformed hmac header")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return errors.New("hmac mismatch")
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultAppHandle is the handle of the app configured with SHOPIFY_API_KEY and SHOPIFY_API_SECRET.
+// Records created before multiple apps were supported belong to it.
+const DefaultAppHandle = "default"
+
+var appHandleRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// App is a Shopify app served by this backend, e.g. one of dev, staging and production listings.
+type App struct {
+	// Handle identifies the app in stored records and in the route prefix of its URLs.
+	Handle string `json:"handle"`
+	// APIKey is the app's client ID.
+	APIKey string `json:"api_key"`
+	// Secrets are the app's client secrets. The first one is current and used for signing,
+	// the others are accepted when verifying signatures while the secret is rotated.
+	Secrets []string `json:"-"`
+}
+
+// Secret returns the current client secret.
+func (a *App) Secret() string {
+	if len(a.Secrets) == 0 {
+		return ""
+	}
+	return a.Secrets[0]
+}
+
+// PathPrefix returns prefix of the app's routes. The default app is served without prefix.
+func (a *App) PathPrefix() string {
+	if a.Handle == DefaultAppHandle {
+		return ""
+	}
+	return "/apps/" + a.Handle
+}
+
+// Apps is a registry of apps served by this backend.
+type Apps struct {
+	apps       []*App
+	defaultApp *App
+	byHandle   map[string]*App
+	byAPIKey   map[string]*App
+}
+
+// NewApps creates registry of the default app and additional apps.
+// Additional apps are a comma separated list of <handle>:<api key>:<secret>[:<previous secret>].
+func NewApps(defaultApp *App, rawApps string) (*Apps, error) {
+	a := &Apps{
+		byHandle: make(map[string]*App),
+		byAPIKey: make(map[string]*App),
+	}
+
+	defaultApp.Handle = DefaultAppHandle
+	err := a.add(defaultApp)
+	if err != nil {
+		return nil, err
+	}
+	a.defaultApp = defaultApp
+
+	for _, rawApp := range strings.Split(rawApps, ",") {
+		rawApp = strings.TrimSpace(rawApp)
+		if rawApp == "" {
+			continue
+		}
+
+		parts := strings.Split(rawApp, ":")
+		if len(parts) < 3 || len(parts) > 4 {
+			return nil, errors.New("app has to be in <handle>:<api key>:<secret>[:<previous secret>] format")
+		}
+		if parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("app %q has to have api key and secret", parts[0])
+		}
+		err = a.add(&App{
+			Handle:  parts[0],
+			APIKey:  parts[1],
+			Secrets: parts[2:],
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+func (a *Apps) add(app *App) error {
+	if !appHandleRegexp.MatchString(app.Handle) {
+		return fmt.Errorf("app handle %q has to contain lowercase letters, digits and hyphens only", app.Handle)
+	}
+	if _, ok := a.byHandle[app.Handle]; ok {
+		return fmt.Errorf("app %q is duplicated", app.Handle)
+	}
+
+	// Secrets of the default app may be empty in development
+	secrets := app.Secrets[:0]
+	for _, secret := range app.Secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	app.Secrets = secrets
+
+	if app.APIKey != "" {
+		if other, ok := a.byAPIKey[app.APIKey]; ok {
+			return fmt.Errorf("apps %q and %q have the same api key", other.Handle, app.Handle)
+		}
+		a.byAPIKey[app.APIKey] = app
+	}
+	a.byHandle[app.Handle] = app
+	a.apps = append(a.apps, app)
+
+	return nil
+}
+
+// Default returns the default app.
+func (a *Apps) Default() *App {
+	return a.defaultApp
+}
+
+// ByHandle returns app with the handle, or nil if there is no such app.
+func (a *Apps) ByHandle(handle string) *App {
+	return a.byHandle[handle]
+}
+
+// ByAPIKey returns app with the api key, or nil if there is no such app.
+func (a *Apps) ByAPIKey(apiKey string) *App {
+	if apiKey == "" {
+		return nil
+	}
+	return a.byAPIKey[apiKey]
+}
+
+// All returns all apps, starting with the default one.
+func (a *Apps) All() []*App {
+	return slices.Clone(a.apps)
+}
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// BulkOperationStatus is a status of bulk operation.
+type BulkOperationStatus string
+
+const (
+	BulkOperationStatusCreated   BulkOperationStatus = "CREATED"
+	BulkOperationStatusRunning   BulkOperationStatus = "RUNNING"
+	BulkOperationStatusCompleted BulkOperationStatus = "COMPLETED"
+	BulkOperationStatusCanceling BulkOperationStatus = "CANCELING"
+	BulkOperationStatusCanceled  BulkOperationStatus = "CANCELED"
+	BulkOperationStatusFailed    BulkOperationStatus = "FAILED"
+	BulkOperationStatusExpired   BulkOperationStatus = "EXPIRED"
+)
+
+// BulkOperation represents a bulk query or mutation run asynchronously by platform.
+type BulkOperation struct {
+	// ID is GraphQL global ID of the operation, e.g. gid://shopify/BulkOperation/1.
+	ID        string              `json:"id"`
+	Type      string              `json:"type"`
+	Status    BulkOperationStatus `json:"status"`
+	ErrorCode string              `json:"error_code"`
+	// ObjectCount is the number of objects processed so far.
+	ObjectCount int64 `json:"object_count"`
+	// URL is the JSONL file with results of completed operation, it's empty if there are no results.
+	URL string `json:"url"`
+	// PartialDataURL is the JSONL file with results processed before the operation failed.
+	PartialDataURL string     `json:"partial_data_url"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+}
+
+// Finished reports whether the operation won't change anymore.
+func (o *BulkOperation) Finished() bool {
+	switch o.Status {
+	case BulkOperationStatusCompleted, BulkOperationStatusCanceled, BulkOperationStatusFailed, BulkOperationStatusExpired:
+		return true
+	}
+	return false
+}
+
+// BulkObject is an object of bulk operation results along with objects of its nested connections.
+// Results contain every object on its own line, nested objects refer to their parent with __parentId.
+type BulkObject struct {
+	// ID is GraphQL global ID of the object, it's empty for results of mutations.
+	ID string
+	// Data is the object's line of results, including __parentId of nested objects.
+	Data json.RawMessage
+	// Children are objects whose parent is the object, in order of results.
+	Children []*BulkObject
+}
+
+// Type returns type of the object from its ID, e.g. ProductVariant for gid://shopify/ProductVariant/1.
+func (o *BulkObject) Type() string {
+	typeName, _, _ := strings.Cut(strings.TrimPrefix(o.ID, "gid://shopify/"), "/")
+	return typeName
+}
+
+// Decode decodes the object's data into v.
+func (o *BulkObject) Decode(v any) error {
+	return json.Unmarshal(o.Data, v)
+}
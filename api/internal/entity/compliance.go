@@ -0,0 +1,61 @@
+package entity
+
+import "time"
+
+// ComplianceRequestStatus is a processing status of compliance request.
+type ComplianceRequestStatus string
+
+const (
+	ComplianceRequestStatusReceived  ComplianceRequestStatus = "received"
+	ComplianceRequestStatusCompleted ComplianceRequestStatus = "completed"
+	ComplianceRequestStatusFailed    ComplianceRequestStatus = "failed"
+)
+
+// ComplianceRequest model represents a privacy request received through mandatory compliance webhooks.
+// Requests are kept after processing as a proof of compliance, but their payloads are erased
+// together with the rest of customer's or store's data.
+type ComplianceRequest struct {
+	ID          string                  `json:"id"`
+	WebhookID   string                  `json:"webhook_id"`
+	Topic       string                  `json:"topic"`
+	App         string                  `json:"app"`
+	StoreName   string                  `json:"store_name"`
+	CustomerID  *int64                  `json:"customer_id"`
+	Payload     *string                 `json:"-"`
+	Status      ComplianceRequestStatus `json:"status"`
+	Error       *string                 `json:"error"`
+	CreatedAt   time.Time               `json:"created_at"`
+	CompletedAt *time.Time              `json:"completed_at"`
+}
+
+// ComplianceCustomer is a customer referenced by compliance webhooks.
+type ComplianceCustomer struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// CustomersDataRequestPayload is a payload of customers/data_request webhook.
+type CustomersDataRequestPayload struct {
+	ShopID          int64              `json:"shop_id"`
+	ShopDomain      string             `json:"shop_domain"`
+	OrdersRequested []int64            `json:"orders_requested"`
+	Customer        ComplianceCustomer `json:"customer"`
+	DataRequest     struct {
+		ID int64 `json:"id"`
+	} `json:"data_request"`
+}
+
+// CustomersRedactPayload is a payload of customers/redact webhook.
+type CustomersRedactPayload struct {
+	ShopID         int64              `json:"shop_id"`
+	ShopDomain     string             `json:"shop_domain"`
+	Customer       ComplianceCustomer `json:"customer"`
+	OrdersToRedact []int64            `json:"orders_to_redact"`
+}
+
+// ShopRedactPayload is a payload of shop/redact webhook.
+type ShopRedactPayload struct {
+	ShopID     int64  `json:"shop_id"`
+	ShopDomain string `json:"shop_domain"`
+}
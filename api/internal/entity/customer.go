@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// Customer model represents a customer of a store.
+type Customer struct {
+	// ID is GraphQL global ID of the customer, e.g. gid://shopify/Customer/1.
+	ID        string    `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
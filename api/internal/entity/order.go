@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// Order model represents an order placed in a store.
+type Order struct {
+	// ID is GraphQL global ID of the order, e.g. gid://shopify/Order/1.
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	Email             string    `json:"email"`
+	Currency          string    `json:"currency"`
+	TotalPrice        string    `json:"total_price"`
+	FinancialStatus   string    `json:"financial_status"`
+	FulfillmentStatus string    `json:"fulfillment_status"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
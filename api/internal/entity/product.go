@@ -0,0 +1,71 @@
+package entity
+
+import "time"
+
+// Product statuses.
+const (
+	ProductStatusActive   = "ACTIVE"
+	ProductStatusDraft    = "DRAFT"
+	ProductStatusArchived = "ARCHIVED"
+)
+
+// Product model represents a product of a store.
+// Options, variants, images and metafields are set only for a single product, not for listed ones.
+type Product struct {
+	// ID is GraphQL global ID of the product, e.g. gid://shopify/Product/1.
+	ID              string           `json:"id"`
+	Title           string           `json:"title"`
+	DescriptionHTML string           `json:"description_html"`
+	Handle          string           `json:"handle"`
+	Vendor          string           `json:"vendor"`
+	ProductType     string           `json:"product_type"`
+	Status          string           `json:"status"`
+	Tags            []string         `json:"tags"`
+	Options         []ProductOption  `json:"options,omitempty"`
+	Variants        []ProductVariant `json:"variants,omitempty"`
+	Images          []ProductImage   `json:"images,omitempty"`
+	Metafields      []Metafield      `json:"metafields,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// ProductOption is an option variants of a product differ in, e.g. size.
+type ProductOption struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Position int      `json:"position"`
+	Values   []string `json:"values"`
+}
+
+// ProductVariant is a variant of a product with a value of every product option.
+type ProductVariant struct {
+	// ID is GraphQL global ID of the variant, e.g. gid://shopify/ProductVariant/1.
+	ID             string `json:"id"`
+	Title          string `json:"title"`
+	SKU            string `json:"sku"`
+	Barcode        string `json:"barcode"`
+	Price          string `json:"price"`
+	CompareAtPrice string `json:"compare_at_price"`
+	// InventoryQuantity is nil if inventory of the variant is not tracked.
+	InventoryQuantity *int `json:"inventory_quantity"`
+	// Options maps name of every product option to the variant's value.
+	Options map[string]string `json:"options"`
+}
+
+// ProductImage is an image of a product.
+type ProductImage struct {
+	// ID is GraphQL global ID of the image's media, e.g. gid://shopify/MediaImage/1.
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	AltText string `json:"alt_text"`
+}
+
+// Metafield is a custom field of a resource.
+type Metafield struct {
+	ID        string `json:"id"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	// Type is the type of the value, e.g. single_line_text_field.
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
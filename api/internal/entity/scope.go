@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+)
+
+// AccessScopes is a normalized set of access scopes granted to or required by the app.
+// https://shopify.dev/docs/api/usage/access-scopes
+type AccessScopes map[string]struct{}
+
+// ParseAccessScopes parses comma separated list of scopes.
+// Scopes are trimmed and lowercased, empty ones are skipped.
+func ParseAccessScopes(raw string) AccessScopes {
+	scopes := AccessScopes{}
+	for _, scope := range strings.Split(raw, ",") {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" {
+			continue
+		}
+		scopes[scope] = struct{}{}
+	}
+	return scopes
+}
+
+// Has reports whether the scope is granted, either directly or implied by a write scope.
+// E.g. write_products implies read_products.
+func (s AccessScopes) Has(scope string) bool {
+	if _, ok := s[scope]; ok {
+		return true
+	}
+
+	// read_X is implied by write_X, unauthenticated_read_X by unauthenticated_write_X
+	prefix, resource, ok := strings.Cut(scope, "read_")
+	if !ok || (prefix != "" && prefix != "unauthenticated_") {
+		return false
+	}
+	_, ok = s[prefix+"write_"+resource]
+	return ok
+}
+
+// Covers reports whether all the required scopes are granted.
+func (s AccessScopes) Covers(required AccessScopes) bool {
+	for scope := range required {
+		if !s.Has(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Missing returns sorted list of the required scopes that are not granted.
+func (s AccessScopes) Missing(required AccessScopes) []string {
+	var missing []string
+	for scope := range required {
+		if !s.Has(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// String returns sorted comma separated list of scopes.
+func (s AccessScopes) String() string {
+	scopes := make([]string, 0, len(s))
+	for scope := range s {
+		scopes = append(scopes, scope)
+	}
+	slices.Sort(scopes)
+	return strings.Join(scopes, ",")
+}
@@ -0,0 +1,82 @@
+package entity
+
+import "time"
+
+// AccessMode defines whether access token is issued to the app (offline) or to a user (online).
+// https://shopify.dev/docs/apps/build/authentication-authorization/access-tokens/about-access-modes
+type AccessMode string
+
+const (
+	// AccessModeOffline access token is used by the app on behalf of the store, e.g. in background jobs.
+	AccessModeOffline AccessMode = "offline"
+	// AccessModeOnline access token is bound to a staff member and limited by their permissions.
+	AccessModeOnline AccessMode = "online"
+)
+
+// Session holds access token used to make requests to store either on behalf of the app (offline)
+// or on behalf of a user (online).
+type Session struct {
+	SessionID   string     `json:"session_id"`
+	StoreID     string     `json:"store_id"`
+	Shop        string     `json:"shop"`
+	IsOnline    bool       `json:"is_online"`
+	UserID      string     `json:"user_id,omitempty"`
+	AccessToken string     `json:"-"` // never marshaled, so it doesn't leak into logs or responses
+	Scope       string     `json:"scope"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	// AssociatedUser is set for online sessions only.
+	AssociatedUser *AssociatedUser `json:"associated_user,omitempty"`
+}
+
+// AssociatedUser is the staff member online access token is issued to.
+type AssociatedUser struct {
+	ID           string `json:"id"`
+	Email        string `json:"email"`
+	AccountOwner bool   `json:"account_owner"`
+	Collaborator bool   `json:"collaborator"`
+	// Scope is the subset of the app's scopes the user has permissions for.
+	Scope string `json:"scope"`
+}
+
+// OfflineSessionID returns ID of the store's offline session of the app.
+func OfflineSessionID(app, shop string) string {
+	return "offline_" + sessionIDPrefix(app) + shop
+}
+
+// OnlineSessionID returns ID of the user's online session of the app in the store.
+func OnlineSessionID(app, shop, userID string) string {
+	return sessionIDPrefix(app) + shop + "_" + userID
+}
+
+// SessionID returns ID of the session of the app with the given access mode.
+func SessionID(mode AccessMode, app, shop, userID string) string {
+	if mode == AccessModeOnline {
+		return OnlineSessionID(app, shop, userID)
+	}
+	return OfflineSessionID(app, shop)
+}
+
+// sessionIDPrefix returns prefix of session IDs of the app.
+// Sessions of the default app keep IDs they had before multiple apps were supported.
+func sessionIDPrefix(app string) string {
+	if app == "" || app == DefaultAppHandle {
+		return ""
+	}
+	return app + "_"
+}
+
+// ExpiresWithin reports whether session's access token expires within the given duration.
+// Access tokens without expiry never expire.
+func (s *Session) ExpiresWithin(d time.Duration) bool {
+	return s.ExpiresAt != nil && time.Now().Add(d).After(*s.ExpiresAt)
+}
+
+// Scopes returns scopes requests made with the session are limited by.
+// Online sessions are limited by permissions of the associated user.
+func (s *Session) Scopes() AccessScopes {
+	if s.IsOnline && s.AssociatedUser != nil {
+		return ParseAccessScopes(s.AssociatedUser.Scope)
+	}
+	return ParseAccessScopes(s.Scope)
+}
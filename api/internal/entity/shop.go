@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/antflydb/shopify-app-template-go/pkg/errs"
+)
+
+// ErrInvalidShopDomain is returned when shop domain is not a *.myshopify.com hostname.
+var ErrInvalidShopDomain = errs.New("invalid shop domain")
+
+var shopDomainRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*\.myshopify\.com$`)
+
+// ShopDomain is a validated and normalized *.myshopify.com hostname of a store.
+// It is safe to be used to build URLs of requests sent to the store.
+type ShopDomain string
+
+// ParseShopDomain trims and lowercases raw shop domain and validates it.
+// Anything except a bare *.myshopify.com hostname is rejected.
+func ParseShopDomain(raw string) (ShopDomain, error) {
+	domain := strings.ToLower(strings.TrimSpace(raw))
+	if !shopDomainRegexp.MatchString(domain) {
+		return "", ErrInvalidShopDomain
+	}
+	return ShopDomain(domain), nil
+}
+
+func (d ShopDomain) String() string {
+	return string(d)
+}
+
+// URL returns https URL of the store with the given path.
+func (d ShopDomain) URL(path string) string {
+	return "https://" + string(d) + path
+}
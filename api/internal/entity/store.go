@@ -1,22 +1,34 @@
 package entity
 
 import (
+	"time"
+
 	"github.com/antflydb/shopify-app-template-go/pkg/database"
 )
 
 // Store model represents model of platform store.
 type Store struct {
 	database.Model
-	ID   string `json:"id"`
+	ID string `json:"id"`
+	// App is handle of the app installed in the store, the same store can install several apps.
+	App  string `json:"app"`
 	Name string `json:"name"`
 
 	// Shopify
-	Nonce       string `json:"nonce"`
-	AccessToken string `json:"access_token"`
-	Installed   bool   `json:"installed"`
+	Nonce          string     `json:"nonce"`
+	NonceCreatedAt *time.Time `json:"nonce_created_at"`
+	AccessToken    string     `json:"-"` // never marshaled, so it doesn't leak into logs or responses
+	Scopes         string     `json:"scopes"`
+	Installed      bool       `json:"installed"`
+
+	// Expiring offline access token is refreshed with refresh token, both are empty for non-expiring ones
+	AccessTokenExpiresAt  *time.Time `json:"access_token_expires_at"`
+	RefreshToken          string     `json:"-"`
+	RefreshTokenExpiresAt *time.Time `json:"refresh_token_expires_at"`
 }
 
-type Session struct {
-	SessionID string `json:"session_id"`
-	StoreID   string `json:"store_id"`
+// AccessTokenExpiresWithin reports whether access token expires within the given duration.
+// Access tokens which don't expire never do.
+func (s *Store) AccessTokenExpiresWithin(d time.Duration) bool {
+	return s.AccessTokenExpiresAt != nil && time.Now().Add(d).After(*s.AccessTokenExpiresAt)
 }
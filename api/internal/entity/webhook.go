@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// ShopWebhookPayload is a payload of shop related webhooks (app/uninstalled, shop/update).
+type ShopWebhookPayload struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	Email           string `json:"email"`
+	Domain          string `json:"domain"`
+	MyshopifyDomain string `json:"myshopify_domain"`
+	PlanName        string `json:"plan_name"`
+	Currency        string `json:"currency"`
+	Timezone        string `json:"iana_timezone"`
+}
+
+// ProductWebhookPayload is a payload of product related webhooks (products/create, products/update).
+type ProductWebhookPayload struct {
+	ID             int64     `json:"id"`
+	AdminGraphqlID string    `json:"admin_graphql_api_id"`
+	Title          string    `json:"title"`
+	Handle         string    `json:"handle"`
+	Vendor         string    `json:"vendor"`
+	ProductType    string    `json:"product_type"`
+	Status         string    `json:"status"`
+	Tags           string    `json:"tags"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// BulkOperationWebhookPayload is a payload of bulk_operations/finish webhook.
+type BulkOperationWebhookPayload struct {
+	AdminGraphqlID string     `json:"admin_graphql_api_id"`
+	Status         string     `json:"status"`
+	Type           string     `json:"type"`
+	ErrorCode      *string    `json:"error_code"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+}
+
+// ProcessedWebhook model represents a webhook delivery that was already handled.
+type ProcessedWebhook struct {
+	WebhookID string `json:"webhook_id"`
+	Topic     string `json:"topic"`
+	// App is handle of the app the webhook was delivered to.
+	App         string    `json:"app"`
+	StoreName   string    `json:"store_name"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// WebhookJobStatus is a processing status of webhook job.
+type WebhookJobStatus string
+
+const (
+	WebhookJobStatusPending WebhookJobStatus = "pending"
+	WebhookJobStatusRunning WebhookJobStatus = "running"
+)
+
+// WebhookJob model represents a received webhook queued for background processing.
+type WebhookJob struct {
+	ID         string           `json:"id"`
+	WebhookID  string           `json:"webhook_id"`
+	Topic      string           `json:"topic"`
+	App        string           `json:"app"`
+	StoreName  string           `json:"store_name"`
+	APIVersion string           `json:"api_version"`
+	Payload    string           `json:"-"`
+	Status     WebhookJobStatus `json:"status"`
+	Attempts   int              `json:"attempts"`
+	RunAt      time.Time        `json:"run_at"`
+	LockedBy   *string          `json:"locked_by"`
+	LockedAt   *time.Time       `json:"locked_at"`
+	LastError  *string          `json:"last_error"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// WebhookSubscription represents a webhook subscription of the app at platform.
+type WebhookSubscription struct {
+	ID string `json:"id"`
+	// Topic is GraphQL enum value of the topic, e.g. PRODUCTS_CREATE, see WebhookTopicEnum.
+	Topic   string `json:"topic"`
+	Address string `json:"address"`
+}
+
+// WebhookTopicEnum returns GraphQL enum value of webhook topic, e.g. PRODUCTS_CREATE for products/create.
+// Topics can't be mapped back reliably, as both resources and actions can contain underscores.
+func WebhookTopicEnum(topic string) string {
+	return strings.ToUpper(strings.ReplaceAll(topic, "/", "_"))
+}
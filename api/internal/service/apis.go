@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"iter"
+	"strings"
+	"time"
 
 	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/pkg/errs"
@@ -14,34 +17,114 @@ type APIs struct {
 
 // PlatformAPI is used to communicate with shop platform.
 type PlatformAPI interface {
+	// VerifyRequest verifies hmac and timestamp that platform signs onto request URL.
+	VerifyRequest(requestURL string) error
 	// HandleInstall verifies installation URL and returns url to redirect user to.
 	HandleInstall(opts HandleInstallOptions) (APIHandleInstallOutput, error)
+	// VerifyRedirect verifies signature, shop and state of redirected URL without using its authorization code.
+	VerifyRedirect(opts APIHandleRedirectOptions) error
 	// HandleRedirect verifies redirected URL and requests access token from shop platform
-	// and then returns the access token.
-	HandleRedirect(opts APIHandleRedirectOptions) (string, error)
-	// SubscribeToAppUninstallWebhook subscribes application to platform's webhook.
-	SubscribeToAppUninstallWebhook(opts SubscribeToAppUninstallWebhookOptions) error
-	// VerifySession verifies session and returns true if session is valid.
-	VerifySession(ctx context.Context) (*VerifySessionOutput, error)
+	// and then returns the access token with granted scopes.
+	HandleRedirect(opts APIHandleRedirectOptions) (*APIHandleRedirectOutput, error)
+	// SignAppURL returns URL of the app's entry point for the store, signed the way platform signs it.
+	// It is used to send user through authorization again.
+	SignAppURL(storeName string) (string, error)
+	// GetAccessScopes returns scopes granted to the app by the store, comma separated.
+	GetAccessScopes(ctx context.Context) (string, error)
+	// ListWebhookSubscriptions returns all webhook subscriptions of the app in store.
+	ListWebhookSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error)
+	// CreateWebhookSubscription subscribes the app to topic's webhooks sent to address.
+	CreateWebhookSubscription(ctx context.Context, topic, address string) (*entity.WebhookSubscription, error)
+	// UpdateWebhookSubscription changes address of webhook subscription.
+	UpdateWebhookSubscription(ctx context.Context, subscriptionID, address string) error
+	// DeleteWebhookSubscription deletes webhook subscription.
+	DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error
+	// VerifySession verifies App Bridge session token and returns details of the session it's issued for.
+	VerifySession(ctx context.Context, sessionToken string) (*VerifySessionOutput, error)
+	// WithApp returns a new instance of PlatformAPI authenticating as the app.
+	WithApp(ctx context.Context, app *entity.App) PlatformAPI
 	// WithConfig returns a new instance of PlatformAPI with provided store config.
 	WithConfig(ctx context.Context, store *entity.Store) PlatformAPI
-	// WithSessionToken returns a new instance of PlatformAPI with session token authentication.
-	WithSessionToken(ctx context.Context, store *entity.Store, sessionToken string) PlatformAPI
-	// CreateProducts creates random products in shopify store.
-	CreateProducts(ctx context.Context) error
+	// WithTokenSource returns a new instance of PlatformAPI authenticated with access token provided by the source.
+	// Requests rejected as unauthorized are retried once with refreshed access token.
+	WithTokenSource(ctx context.Context, storeName string, source TokenSource) PlatformAPI
+	// RefreshAccessToken exchanges refresh token for a new expiring offline access token.
+	RefreshAccessToken(ctx context.Context, storeName, refreshToken string) (*APIRefreshAccessTokenOutput, error)
+	// WithSession returns a new instance of PlatformAPI authenticated with session's access token.
+	WithSession(ctx context.Context, session *entity.Session) PlatformAPI
+	// ExchangeSessionToken exchanges session token for access token with the given access mode.
+	ExchangeSessionToken(ctx context.Context, storeName, sessionToken string, mode entity.AccessMode) (*APIExchangeSessionTokenOutput, error)
 	// GetProductsCount returns number of products in store.
 	GetProductsCount(ctx context.Context) (int, error)
+	// ListProducts returns iterator over products in store matching the search query, or all of them if it's empty.
+	// Pages of first products are requested as they are iterated, pages are as large as possible if it's zero.
+	// Options, variants, images and metafields of products are not listed.
+	ListProducts(ctx context.Context, query string, first int) iter.Seq2[entity.Product, error]
+	// GetProduct returns product with its options, variants, images and metafields, or nil if it's not found.
+	GetProduct(ctx context.Context, productID string) (*entity.Product, error)
+	// CreateProduct creates product and returns it.
+	CreateProduct(ctx context.Context, input *ProductInput) (*entity.Product, error)
+	// UpdateProduct replaces product with input and returns it, ErrProductNotFound is returned if it doesn't exist.
+	UpdateProduct(ctx context.Context, productID string, input *ProductInput) (*entity.Product, error)
+	// DeleteProduct deletes product, ErrProductNotFound is returned if it doesn't exist.
+	DeleteProduct(ctx context.Context, productID string) error
+	// ListOrders returns iterator over all orders in store, pages are requested as they are iterated.
+	ListOrders(ctx context.Context) iter.Seq2[entity.Order, error]
+	// ListCustomers returns iterator over all customers in store, pages are requested as they are iterated.
+	ListCustomers(ctx context.Context) iter.Seq2[entity.Customer, error]
+	// RunBulkQuery starts bulk operation running the query over all objects it selects.
+	RunBulkQuery(ctx context.Context, query string) (*entity.BulkOperation, error)
+	// RunBulkMutation uploads variables as JSONL file and starts bulk operation running the mutation once for every variables.
+	RunBulkMutation(ctx context.Context, mutation string, variables []map[string]any) (*entity.BulkOperation, error)
+	// GetBulkOperation returns bulk operation by its ID, or nil if it's not found.
+	GetBulkOperation(ctx context.Context, operationID string) (*entity.BulkOperation, error)
+	// BulkOperationResults returns iterator over top-level objects of bulk operation results with their nested objects.
+	// Results are downloaded and read line by line as they are iterated.
+	BulkOperationResults(ctx context.Context, resultsURL string) iter.Seq2[*entity.BulkObject, error]
 }
 
 var (
+	// ErrVerifyRequestInvalidSignature is returned when request URL is not signed by platform.
+	ErrVerifyRequestInvalidSignature = errs.New("invalid request signature")
+	// ErrVerifyRequestExpired is returned when request URL was signed too long ago.
+	ErrVerifyRequestExpired = errs.New("request has expired")
 	// ErrHandleRedirectInvalidRedirectedURL is returned when provided redirected URL is invalid.
 	ErrHandleRedirectInvalidRedirectedURL = errs.New("invalid redirected url")
 	// ErrHandleRedirectInvalidScopes is returned when user didn't allow all the requested scopes when installing app.
 	ErrHandleRedirectInvalidScopes = errs.New("allowed access scopes are different from requested")
 )
 
+// APIUserError is an error of input platform rejected, e.g. a product without title.
+type APIUserError struct {
+	// Field is the path to the input field the error relates to.
+	Field   []string `json:"field,omitempty"`
+	Message string   `json:"message"`
+	Code    string   `json:"code,omitempty"`
+}
+
+// APIUserErrors is returned when platform rejects input of a mutation.
+type APIUserErrors []APIUserError
+
+func (e APIUserErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, userErr := range e {
+		if len(userErr.Field) == 0 {
+			messages = append(messages, userErr.Message)
+			continue
+		}
+		messages = append(messages, strings.Join(userErr.Field, ".")+": "+userErr.Message)
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
 type VerifySessionOutput struct {
-	StoreName  string
+	// App is handle of the app session token is issued for.
+	App       string
+	StoreName string
+	UserID    string
+	// SessionID is ID of the user's Shopify admin session.
+	SessionID  string
+	ExpiresAt  time.Time
 	IsVerified bool
 }
 
@@ -49,6 +132,8 @@ type HandleInstallOptions struct {
 	InstallationURL string
 	RedirectURL     string
 	StoreName       string
+	// AccessMode is the access mode of the access token to request.
+	AccessMode entity.AccessMode
 }
 
 type APIHandleInstallOutput struct {
@@ -59,11 +144,44 @@ type APIHandleInstallOutput struct {
 type APIHandleRedirectOptions struct {
 	Nonce         string
 	RedirectedURL string
-	StoreName     string
+	// StoreName is the name of the store holding the nonce, callback's shop has to match it.
+	StoreName string
 }
 
-type SubscribeToAppUninstallWebhookOptions struct {
-	RedirectURL string
-	StoreName   string
+type APIHandleRedirectOutput struct {
+	AccessToken string
+	// Scopes is normalized list of granted access scopes.
+	Scopes string
+	// ExpiresAt, RefreshToken and RefreshTokenExpiresAt are set for expiring access tokens only.
+	ExpiresAt             *time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt *time.Time
+}
+
+type APIRefreshAccessTokenOutput struct {
+	AccessToken string
+	// Scopes is normalized list of granted access scopes.
+	Scopes string
+	// ExpiresAt, RefreshToken and RefreshTokenExpiresAt are set for expiring access tokens only.
+	ExpiresAt             *time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt *time.Time
+}
+
+// TokenSource provides access token of a store.
+type TokenSource interface {
+	// Token returns valid access token, refreshing it if it's about to expire.
+	Token(ctx context.Context) (string, error)
+	// Refresh returns new access token after the given one was rejected.
+	Refresh(ctx context.Context, rejected string) (string, error)
+}
+
+type APIExchangeSessionTokenOutput struct {
 	AccessToken string
+	// Scopes is normalized list of granted access scopes.
+	Scopes string
+	// ExpiresAt is nil for access tokens which don't expire.
+	ExpiresAt *time.Time
+	// AssociatedUser is set for online access tokens only.
+	AssociatedUser *entity.AssociatedUser
 }
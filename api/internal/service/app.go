@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/pkg/errs"
+)
+
+// ErrUnknownApp is returned when app a request or record belongs to is not configured.
+var ErrUnknownApp = errs.New("unknown app")
+
+// appContextKey is used to store app the request is made to in request context.
+type appContextKey struct{}
+
+// ContextWithApp returns context carrying the app the request is made to.
+func ContextWithApp(ctx context.Context, app *entity.App) context.Context {
+	return context.WithValue(ctx, appContextKey{}, app)
+}
+
+// AppFromContext returns app the request is made to, or nil if it's not resolved.
+func AppFromContext(ctx context.Context) *entity.App {
+	app, _ := ctx.Value(appContextKey{}).(*entity.App)
+	return app
+}
+
+// app returns app the request is made to: the app session token is issued for,
+// the app resolved from the request's route, or the default app.
+func (s *platformService) app(ctx context.Context) *entity.App {
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		if app := s.apps.ByHandle(principal.App); app != nil {
+			return app
+		}
+	}
+	if app := AppFromContext(ctx); app != nil {
+		return app
+	}
+	return s.apps.Default()
+}
+
+// api returns PlatformAPI using credentials of the app the request is made to.
+func (s *platformService) api(ctx context.Context) PlatformAPI {
+	return s.apis.Platform.WithApp(ctx, s.app(ctx))
+}
+
+// storeApp returns app the store has installed.
+func (s *platformService) storeApp(store *entity.Store) (*entity.App, error) {
+	app := s.apps.ByHandle(store.App)
+	if app == nil {
+		return nil, fmt.Errorf("%w %q of store %s", ErrUnknownApp, store.App, store.Name)
+	}
+	return app, nil
+}
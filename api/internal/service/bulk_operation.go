@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+// bulkOperationWaiter is closed when bulk_operations/finish webhook of the operation is received.
+type bulkOperationWaiter struct {
+	once sync.Once
+	done chan struct{}
+}
+
+func (w *bulkOperationWaiter) wake() {
+	w.once.Do(func() { close(w.done) })
+}
+
+func (s *platformService) RunBulkQuery(ctx context.Context, store *entity.Store, query string) (*entity.BulkOperation, error) {
+	logger := s.logger.
+		Named("RunBulkQuery").
+		WithContext(ctx).
+		With("app", store.App, "storeName", store.Name)
+
+	app, err := s.storeApp(store)
+	if err != nil {
+		logger.Error("failed to resolve store's app", "err", err)
+		return nil, err
+	}
+
+	operation, err := s.storeAPI(ctx, app, store).RunBulkQuery(ctx, query)
+	if err != nil {
+		logger.Error("failed to run bulk query", "err", err)
+		return nil, fmt.Errorf("failed to run bulk query: %w", err)
+	}
+
+	return operation, nil
+}
+
+func (s *platformService) RunBulkMutation(ctx context.Context, store *entity.Store, mutation string, variables []map[string]any) (*entity.BulkOperation, error) {
+	logger := s.logger.
+		Named("RunBulkMutation").
+		WithContext(ctx).
+		With("app", store.App, "storeName", store.Name)
+
+	app, err := s.storeApp(store)
+	if err != nil {
+		logger.Error("failed to resolve store's app", "err", err)
+		return nil, err
+	}
+
+	operation, err := s.storeAPI(ctx, app, store).RunBulkMutation(ctx, mutation, variables)
+	if err != nil {
+		logger.Error("failed to run bulk mutation", "err", err)
+		return nil, fmt.Errorf("failed to run bulk mutation: %w", err)
+	}
+
+	return operation, nil
+}
+
+func (s *platformService) WaitBulkOperation(ctx context.Context, store *entity.Store, operationID string) (*entity.BulkOperation, error) {
+	logger := s.logger.
+		Named("WaitBulkOperation").
+		WithContext(ctx).
+		With("app", store.App, "storeName", store.Name, "operationID", operationID)
+
+	app, err := s.storeApp(store)
+	if err != nil {
+		logger.Error("failed to resolve store's app", "err", err)
+		return nil, err
+	}
+	api := s.storeAPI(ctx, app, store)
+
+	// Webhook can be delivered to another instance, so the operation is polled anyway
+	waiter := &bulkOperationWaiter{done: make(chan struct{})}
+	actual, _ := s.bulkOperationWaiters.LoadOrStore(operationID, waiter)
+	waiter = actual.(*bulkOperationWaiter)
+	defer s.bulkOperationWaiters.CompareAndDelete(operationID, waiter)
+	done := waiter.done
+
+	ticker := time.NewTicker(s.config.Shopify.BulkOperationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		operation, err := api.GetBulkOperation(ctx, operationID)
+		if err != nil {
+			logger.Error("failed to get bulk operation", "err", err)
+			return nil, fmt.Errorf("failed to get bulk operation: %w", err)
+		}
+		if operation == nil {
+			logger.Info("bulk operation is not found")
+			return nil, ErrBulkOperationNotFound
+		}
+		if operation.Finished() {
+			logger.Info("bulk operation is finished", "status", operation.Status, "errorCode", operation.ErrorCode, "objectCount", operation.ObjectCount)
+			return operation, nil
+		}
+		logger.Debug("bulk operation is not finished", "status", operation.Status, "objectCount", operation.ObjectCount)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-done:
+			// Webhook is received once, the operation is polled on the next tick if it's not finished yet
+			done = nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *platformService) BulkOperationResults(ctx context.Context, store *entity.Store, operation *entity.BulkOperation) iter.Seq2[*entity.BulkObject, error] {
+	return func(yield func(*entity.BulkObject, error) bool) {
+		if operation.Status != entity.BulkOperationStatusCompleted {
+			yield(nil, ErrBulkOperationNotCompleted)
+			return
+		}
+		// Operation without results has no file
+		if operation.URL == "" {
+			return
+		}
+
+		app, err := s.storeApp(store)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for object, err := range s.apis.Platform.WithApp(ctx, app).BulkOperationResults(ctx, operation.URL) {
+			if !yield(object, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *platformService) HandleBulkOperationsFinish(ctx context.Context, webhook *Webhook, payload *entity.BulkOperationWebhookPayload) error {
+	logger := s.logger.
+		Named("HandleBulkOperationsFinish").
+		WithContext(ctx).
+		With("app", webhook.App, "storeName", webhook.StoreName, "operationID", payload.AdminGraphqlID, "status", payload.Status)
+
+	// Nobody waits for operation started by another instance or before restart
+	waiter, ok := s.bulkOperationWaiters.Load(payload.AdminGraphqlID)
+	if !ok {
+		logger.Debug("nobody waits for bulk operation")
+		return nil
+	}
+	waiter.(*bulkOperationWaiter).wake()
+
+	logger.Info("woke up waiters of bulk operation")
+	return nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+func (s *platformService) HandleCustomersDataRequest(ctx context.Context, webhook *Webhook, payload *entity.CustomersDataRequestPayload) error {
+	logger := s.logger.
+		Named("HandleCustomersDataRequest").
+		WithContext(ctx).
+		With("storeName", webhook.StoreName, "customerID", payload.Customer.ID, "dataRequestID", payload.DataRequest.ID)
+
+	return s.processComplianceRequest(ctx, webhook, &payload.Customer.ID, func() error {
+		// The template keeps no customer data besides compliance requests themselves,
+		// so recording the request is all that has to be done.
+		logger.Info("recorded customer data request", "ordersRequested", payload.OrdersRequested)
+		return nil
+	})
+}
+
+func (s *platformService) HandleCustomersRedact(ctx context.Context, webhook *Webhook, payload *entity.CustomersRedactPayload) error {
+	logger := s.logger.
+		Named("HandleCustomersRedact").
+		WithContext(ctx).
+		With("storeName", webhook.StoreName, "customerID", payload.Customer.ID)
+
+	return s.processComplianceRequest(ctx, webhook, &payload.Customer.ID, func() error {
+		err := s.storages.ComplianceRequest.RedactCustomer(ctx, webhook.App, webhook.StoreName, payload.Customer.ID)
+		if err != nil {
+			logger.Error("failed to redact customer's compliance requests", "err", err)
+			return fmt.Errorf("failed to redact customer's compliance requests: %w", err)
+		}
+
+		logger.Info("redacted customer's data")
+		return nil
+	})
+}
+
+func (s *platformService) HandleShopRedact(ctx context.Context, webhook *Webhook, payload *entity.ShopRedactPayload) error {
+	logger := s.logger.
+		Named("HandleShopRedact").
+		WithContext(ctx).
+		With("storeName", webhook.StoreName, "shopID", payload.ShopID)
+
+	return s.processComplianceRequest(ctx, webhook, nil, func() error {
+		err := s.storages.Store.Erase(ctx, webhook.App, webhook.StoreName)
+		if err != nil {
+			logger.Error("failed to erase store from storage", "err", err)
+			return fmt.Errorf("failed to erase store from storage: %w", err)
+		}
+
+		err = s.storages.ComplianceRequest.RedactStore(ctx, webhook.App, webhook.StoreName)
+		if err != nil {
+			logger.Error("failed to redact store's compliance requests", "err", err)
+			return fmt.Errorf("failed to redact store's compliance requests: %w", err)
+		}
+
+		logger.Info("erased store's data")
+		return nil
+	})
+}
+
+// processComplianceRequest records compliance request, runs the handler and persists its outcome.
+func (s *platformService) processComplianceRequest(ctx context.Context, webhook *Webhook, customerID *int64, handler func() error) error {
+	logger := s.logger.
+		Named("processComplianceRequest").
+		WithContext(ctx).
+		With("webhookID", webhook.ID, "topic", webhook.Topic, "storeName", webhook.StoreName)
+
+	payload := string(webhook.Payload)
+	request, err := s.storages.ComplianceRequest.Create(ctx, &entity.ComplianceRequest{
+		WebhookID:  webhook.ID,
+		Topic:      webhook.Topic,
+		App:        webhook.App,
+		StoreName:  webhook.StoreName,
+		CustomerID: customerID,
+		Payload:    &payload,
+		Status:     entity.ComplianceRequestStatusReceived,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		logger.Error("failed to record compliance request", "err", err)
+		return fmt.Errorf("failed to record compliance request: %w", err)
+	}
+	logger = logger.With("complianceRequestID", request.ID)
+
+	handlerErr := handler()
+
+	now := time.Now()
+	request.CompletedAt = &now
+	request.Status = entity.ComplianceRequestStatusCompleted
+	if handlerErr != nil {
+		errMessage := handlerErr.Error()
+		request.Status = entity.ComplianceRequestStatusFailed
+		request.Error = &errMessage
+	}
+
+	_, err = s.storages.ComplianceRequest.Update(ctx, request)
+	if err != nil {
+		logger.Error("failed to record compliance request outcome", "err", err)
+		return fmt.Errorf("failed to record compliance request outcome: %w", err)
+	}
+
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	logger.Info("processed compliance request")
+	return nil
+}
@@ -2,9 +2,9 @@ package service
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/antflydb/shopify-app-template-go/config"
 	"github.com/antflydb/shopify-app-template-go/internal/entity"
@@ -16,90 +16,141 @@ import (
 type platformService struct {
 	apis     APIs
 	storages Storages
+	webhooks *WebhookRouter
+	apps     *entity.Apps
 	config   *config.Config
 	logger   logging.Logger
+
+	// refreshLocks holds *sync.Mutex per app and store name, so access token of a store is refreshed once at a time
+	refreshLocks sync.Map
+	// bulkOperationWaiters holds *bulkOperationWaiter per ID of bulk operation being waited for
+	bulkOperationWaiters sync.Map
 }
 
 var _ PlatformService = (*platformService)(nil)
 
 func NewPlatformService(opts *Options) *platformService {
-	return &platformService{
+	s := &platformService{
 		apis:     opts.Apis,
 		storages: opts.Storages,
+		webhooks: opts.Webhooks,
+		apps:     opts.Apps,
 		config:   opts.Config,
 		logger:   opts.Logger.Named("Platform"),
 	}
+
+	HandleWebhook(opts.Webhooks, WebhookTopicAppUninstalled, s.handleAppUninstalledWebhook)
+	HandleWebhook(opts.Webhooks, WebhookTopicShopUpdate, s.handleShopUpdateWebhook)
+	HandleWebhook(opts.Webhooks, WebhookTopicProductsCreate, s.handleProductWebhook)
+	HandleWebhook(opts.Webhooks, WebhookTopicProductsUpdate, s.handleProductWebhook)
+	HandleWebhook(opts.Webhooks, WebhookTopicCustomersDataRequest, s.HandleCustomersDataRequest)
+	HandleWebhook(opts.Webhooks, WebhookTopicCustomersRedact, s.HandleCustomersRedact)
+	HandleWebhook(opts.Webhooks, WebhookTopicShopRedact, s.HandleShopRedact)
+	HandleWebhook(opts.Webhooks, WebhookTopicBulkOperationsFinish, s.HandleBulkOperationsFinish)
+
+	return s
 }
 
-func (s *platformService) Handle(ctx context.Context, storeName, installationURL string) (string, error) {
-	logger := s.logger.Named("Handle").WithContext(ctx)
+func (s *platformService) Handle(ctx context.Context, storeName, installationURL string) (*HandleOutput, error) {
+	app := s.app(ctx)
+	logger := s.logger.Named("Handle").WithContext(ctx).With("app", app.Handle)
+
+	// Verify that the request is signed by platform before trusting the shop parameter
+	err := s.api(ctx).VerifyRequest(installationURL)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error(), "storeName", storeName)
+			return nil, err
+		}
+		logger.Error("failed to verify request", "err", err)
+		return nil, fmt.Errorf("failed to verify request: %w", err)
+	}
 
 	// Check if store is not already installed
-	store, err := s.storages.Store.Get(ctx, storeName)
+	store, err := s.storages.Store.Get(ctx, app.Handle, storeName)
 	if err != nil {
 		logger.Error("failed to get store from storage", "err", err)
-		return "", fmt.Errorf("failed to get store from storage: %w", err)
+		return nil, fmt.Errorf("failed to get store from storage: %w", err)
 	}
 	logger = logger.With("store", store)
 	logger.Debug("got store")
 
+	// Store installed before scopes were stored goes through authorization again if they can't be refreshed
 	if store != nil && store.Installed {
+		err = s.refreshStoreScopes(ctx, store)
+		if err != nil {
+			logger.Error("failed to refresh store scopes", "err", err)
+		}
+	}
+
+	// Store which hasn't granted all the required scopes goes through authorization again
+	if store != nil && store.Installed && s.hasRequiredScopes(store) {
 		logger.Info("store is already installed")
-		return fmt.Sprintf("https://%s/admin/apps/%s/exit-iframe", storeName, s.config.Shopify.ApiKey), nil
+		return &HandleOutput{
+			RedirectURL: fmt.Sprintf("https://%s/admin/apps/%s/exit-iframe", storeName, app.APIKey),
+		}, nil
 	}
 
-	res, err := s.apis.Platform.HandleInstall(HandleInstallOptions{
+	// Store is installed with offline access token, as it's used in background, e.g. to handle webhooks
+	res, err := s.api(ctx).HandleInstall(HandleInstallOptions{
 		InstallationURL: installationURL,
-		RedirectURL:     s.config.App.BaseURL + "/auth/callback",
+		RedirectURL:     s.config.App.BaseURL + app.PathPrefix() + "/auth/callback",
 		StoreName:       storeName,
+		AccessMode:      entity.AccessModeOffline,
 	})
 	if err != nil {
 		logger.Info(err.Error())
-		return "", err
+		return nil, err
 	}
-	logger = logger.With("res", res)
+	logger = logger.With("redirectURL", res.RedirectURL)
 	logger.Debug("handled install on api side")
 
 	// Create new instance of a store in db or update existing one with nonce
+	nonceCreatedAt := time.Now()
 	if store == nil {
-		logger.Info("creating new store", "storeName", storeName, "nonce", res.Nonce)
+		logger.Info("creating new store", "storeName", storeName)
 		createdStore, err := s.storages.Store.Create(ctx, &entity.Store{
-			Name:      storeName,
-			Nonce:     res.Nonce,
-			Installed: false,
+			App:            app.Handle,
+			Name:           storeName,
+			Nonce:          res.Nonce,
+			NonceCreatedAt: &nonceCreatedAt,
+			Installed:      false,
 		})
 		if err != nil {
 			logger.Error("failed to create store in storage", "err", err)
-			return "", fmt.Errorf("failed to create store in storage: %w", err)
+			return nil, fmt.Errorf("failed to create store in storage: %w", err)
 		}
 		logger = logger.With("createdStore", createdStore)
 		logger.Info("successfully created store", "storeId", createdStore.ID, "storeName", createdStore.Name)
 	} else {
-		logger.Info("updating existing store with new nonce", "storeName", storeName, "oldNonce", store.Nonce, "newNonce", res.Nonce)
-		updatedStore, err := s.storages.Store.Update(ctx, &entity.Store{
-			Name:      storeName,
-			Nonce:     res.Nonce,
-			Installed: false,
-		})
+		// Installed store keeps its access token until it's authorized again
+		logger.Info("updating existing store with new nonce", "storeName", storeName)
+		store.Nonce = res.Nonce
+		store.NonceCreatedAt = &nonceCreatedAt
+		updatedStore, err := s.storages.Store.Update(ctx, store)
 		if err != nil {
 			logger.Error("failed to updated store in storage", "err", err)
-			return "", fmt.Errorf("failed to create store in storage: %w", err)
+			return nil, fmt.Errorf("failed to create store in storage: %w", err)
 		}
 		logger = logger.With("updatedStore", updatedStore)
 		logger.Info("successfully updated store with new nonce", "storeId", updatedStore.ID, "storeName", updatedStore.Name)
 	}
 	logger.Info("got redirect url and saved store's nonce into db")
 
-	return res.RedirectURL, nil
+	return &HandleOutput{
+		RedirectURL: res.RedirectURL,
+		Nonce:       res.Nonce,
+	}, nil
 }
 
 func (s *platformService) HandleRedirect(ctx context.Context, opts ServiceHandleRedirectOptions) error {
+	app := s.app(ctx)
 	logger := s.logger.
 		Named("HandleRedirect").
-		With("opts", opts)
+		With("app", app.Handle, "opts", opts)
 
 	// Check if store exists
-	store, err := s.storages.Store.Get(ctx, opts.StoreName)
+	store, err := s.storages.Store.Get(ctx, app.Handle, opts.StoreName)
 	if err != nil {
 		logger.Error("failed to get store from storage", "err", err)
 		return fmt.Errorf("failed to get store from storage: %w", err)
@@ -111,38 +162,60 @@ func (s *platformService) HandleRedirect(ctx context.Context, opts ServiceHandle
 	logger = logger.With("store", store)
 	logger.Debug("got store for redirect")
 
-	accessToken, err := s.apis.Platform.HandleRedirect(APIHandleRedirectOptions{
-		Nonce:         store.Nonce,
+	nonce := store.Nonce
+	if nonce == "" || store.NonceCreatedAt == nil || time.Since(*store.NonceCreatedAt) > s.config.Shopify.OAuthStateTTL {
+		logger.Info("nonce is missing or expired")
+		return ErrHandleRedirectNonceExpired
+	}
+
+	// Forged callbacks are rejected before the nonce is consumed, so they can't cancel authorization in progress
+	redirectOpts := APIHandleRedirectOptions{
+		Nonce:         nonce,
 		RedirectedURL: opts.RedirectedURL,
-		StoreName:     opts.StoreName,
-	})
+		StoreName:     store.Name,
+	}
+	err = s.api(ctx).VerifyRedirect(redirectOpts)
 	if err != nil {
 		if errs.IsExpected(err) {
 			logger.Info(err.Error())
 			return err
 		}
-		logger.Error("failed to handle redirect at API ", "err", err)
-		return fmt.Errorf("failed to retrieve access token from api: %w", err)
+		logger.Error("failed to verify redirect", "err", err)
+		return fmt.Errorf("failed to verify redirect: %w", err)
 	}
-	logger.Debug("got access token")
 
-	err = s.apis.Platform.SubscribeToAppUninstallWebhook(SubscribeToAppUninstallWebhookOptions{
-		RedirectURL: fmt.Sprintf("%s/uninstall?shop=%s", s.config.App.BaseURL, opts.StoreName),
-		StoreName:   opts.StoreName,
-		AccessToken: accessToken,
-	})
+	// Nonce is single-use, so only one of concurrent callbacks with it gets the access token
+	consumed, err := s.storages.Store.ConsumeNonce(ctx, app.Handle, store.Name, nonce)
+	if err != nil {
+		logger.Error("failed to consume store's nonce", "err", err)
+		return fmt.Errorf("failed to consume store's nonce: %w", err)
+	}
+	if !consumed {
+		logger.Info("nonce is already consumed")
+		return ErrHandleRedirectNonceExpired
+	}
+	store.Nonce = ""
+	store.NonceCreatedAt = nil
+
+	credentials, err := s.api(ctx).HandleRedirect(redirectOpts)
 	if err != nil {
-		logger.Error("failed to subscribe to app uninstalled webhook", "err", err)
-		return fmt.Errorf("failed to subscribe to app uninstalled webhook: %w", err)
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return err
+		}
+		logger.Error("failed to handle redirect at API ", "err", err)
+		return fmt.Errorf("failed to retrieve access token from api: %w", err)
 	}
-	logger.Debug("subscribed to webhook")
+	logger.Debug("got access token")
 
 	logger.Info("marking store as installed", "storeName", opts.StoreName)
-	updatedStore, err := s.storages.Store.Update(ctx, &entity.Store{
-		Name:        opts.StoreName,
-		AccessToken: accessToken,
-		Installed:   true,
-	})
+	store.AccessToken = credentials.AccessToken
+	store.Scopes = credentials.Scopes
+	store.AccessTokenExpiresAt = credentials.ExpiresAt
+	store.RefreshToken = credentials.RefreshToken
+	store.RefreshTokenExpiresAt = credentials.RefreshTokenExpiresAt
+	store.Installed = true
+	updatedStore, err := s.storages.Store.Update(ctx, store)
 	if err != nil {
 		logger.Error("failed to update store in storage", "err", err)
 		return fmt.Errorf("failed to update store in storage: %w", err)
@@ -150,15 +223,30 @@ func (s *platformService) HandleRedirect(ctx context.Context, opts ServiceHandle
 	logger = logger.With("updatedStore", updatedStore)
 	logger.Info("successfully marked store as installed", "storeId", updatedStore.ID, "storeName", updatedStore.Name, "installed", updatedStore.Installed)
 
+	err = s.saveOfflineSession(ctx, updatedStore)
+	if err != nil {
+		logger.Error("failed to save offline session", "err", err)
+		return fmt.Errorf("failed to save offline session: %w", err)
+	}
+
+	// Store is already installed, so failed subscriptions are fixed by the periodic reconciliation instead
+	_, err = s.reconcileWebhookSubscriptions(ctx, updatedStore)
+	if err != nil {
+		logger.Error("failed to reconcile webhook subscriptions, they are reconciled later", "err", err)
+		return nil
+	}
+	logger.Debug("subscribed to webhooks")
+
 	return nil
 }
 
 func (s *platformService) HandleUninstall(ctx context.Context, storeName string) error {
+	app := s.app(ctx)
 	logger := s.logger.
 		Named("HandleUninstall").
-		With("storeName", storeName)
+		With("app", app.Handle, "storeName", storeName)
 
-	store, err := s.storages.Store.Get(ctx, storeName)
+	store, err := s.storages.Store.Get(ctx, app.Handle, storeName)
 	if err != nil {
 		logger.Error("failed to get store from storage", "err", err)
 		return fmt.Errorf("failed to get store from storage: %w", err)
@@ -170,127 +258,207 @@ func (s *platformService) HandleUninstall(ctx context.Context, storeName string)
 	logger = logger.With("store", store)
 	logger.Debug("got store")
 
-	err = s.storages.Store.Delete(ctx, storeName)
+	err = s.storages.Store.Delete(ctx, app.Handle, storeName)
 	if err != nil {
 		logger.Error("failed to delete store from storage", "err", err)
 		return fmt.Errorf("failed to delete store from storage: %w", err)
 	}
 
+	// Access tokens are revoked when the app is uninstalled
+	deleted, err := s.storages.Session.DeleteByStore(ctx, store.ID)
+	if err != nil {
+		logger.Error("failed to delete store's sessions", "err", err)
+		return fmt.Errorf("failed to delete store's sessions: %w", err)
+	}
+	logger.Debug("deleted store's sessions", "deleted", deleted)
+
 	logger.Info("successfully deleted store's config")
 	return nil
 }
 
-func (s *platformService) CreateProducts(ctx context.Context) error {
-	logger := s.logger.Named("CreateProducts").WithContext(ctx)
+// handleAppUninstalledWebhook handles app/uninstalled webhook.
+func (s *platformService) handleAppUninstalledWebhook(ctx context.Context, webhook *Webhook, _ *entity.ShopWebhookPayload) error {
+	return s.HandleUninstall(ctx, webhook.StoreName)
+}
 
-	output, err := s.apis.Platform.VerifySession(ctx)
-	if err != nil {
-		logger.Error("failed to verify session", "err", err)
-		return fmt.Errorf("failed to verify session: %w", err)
+// handleShopUpdateWebhook handles shop/update webhook.
+// The template keeps no shop details, so changes are only logged.
+func (s *platformService) handleShopUpdateWebhook(ctx context.Context, webhook *Webhook, payload *entity.ShopWebhookPayload) error {
+	s.logger.
+		Named("handleShopUpdateWebhook").
+		WithContext(ctx).
+		With("app", webhook.App, "storeName", webhook.StoreName).
+		Info("shop is updated", "name", payload.Name, "planName", payload.PlanName, "currency", payload.Currency)
+	return nil
+}
+
+func (s *platformService) VerifySessionToken(ctx context.Context, sessionToken string) (*Principal, error) {
+	logger := s.logger.Named("VerifySessionToken").WithContext(ctx)
+
+	output, err := s.apis.Platform.VerifySession(ctx, sessionToken)
+	if err != nil || !output.IsVerified {
+		logger.Info("invalid session token", "err", err)
+		return nil, ErrInvalidSessionToken
 	}
-	if !output.IsVerified {
-		logger.Info("invalid session")
-		return errors.New("invalid session")
+
+	return &Principal{
+		App:          output.App,
+		Shop:         output.StoreName,
+		UserID:       output.UserID,
+		SessionID:    output.SessionID,
+		ExpiresAt:    output.ExpiresAt,
+		SessionToken: sessionToken,
+	}, nil
+}
+
+// hasRequiredScopes reports whether store has granted all the scopes the app is configured with.
+func (s *platformService) hasRequiredScopes(store *entity.Store) bool {
+	return entity.ParseAccessScopes(store.Scopes).Covers(entity.ParseAccessScopes(s.config.Shopify.Scopes))
+}
+
+// refreshStoreScopes fills unknown scopes of store, e.g. installed before scopes were stored,
+// with scopes granted to its offline access token.
+func (s *platformService) refreshStoreScopes(ctx context.Context, store *entity.Store) error {
+	if store.Scopes != "" || store.AccessToken == "" {
+		return nil
 	}
 
-	store, err := s.storages.Store.Get(ctx, output.StoreName)
+	app, err := s.storeApp(store)
 	if err != nil {
-		logger.Error("failed to get store from storage", "err", err)
-		return fmt.Errorf("failed to get store from storage: %w", err)
+		return err
 	}
-	if store == nil {
-		logger.Info("store not found, creating new store", "store_name", output.StoreName)
-		store, err = s.storages.Store.Create(ctx, &entity.Store{
-			Name:      output.StoreName,
-			Installed: true,
-		})
-		if err != nil {
-			logger.Error("failed to create store", "err", err)
-			return fmt.Errorf("failed to create store: %w", err)
-		}
-		logger.Info("successfully created store", "storeId", store.ID, "storeName", store.Name)
+	scopes, err := s.storeAPI(ctx, app, store).GetAccessScopes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get access scopes: %w", err)
 	}
+	return s.saveStoreScopes(ctx, store, scopes)
+}
 
-	// Get session token from context for API calls
-	sessionToken := getSessionTokenFromContext(ctx)
-	if sessionToken == "" {
-		logger.Error("missing session token for API call")
-		return fmt.Errorf("missing session token for API call")
+// saveStoreScopes saves scopes granted by store if they've changed.
+func (s *platformService) saveStoreScopes(ctx context.Context, store *entity.Store, scopes string) error {
+	if scopes == "" || scopes == store.Scopes {
+		return nil
 	}
 
-	err = s.apis.Platform.WithSessionToken(ctx, store, sessionToken).CreateProducts(ctx)
+	store.Scopes = scopes
+	err := s.storages.Store.UpdateScopes(ctx, store.App, store.Name, scopes)
 	if err != nil {
-		logger.Error("failed to create products", "err", err)
-		return fmt.Errorf("failed to create products: %w", err)
+		return fmt.Errorf("failed to update store scopes: %w", err)
 	}
-
 	return nil
 }
 
-func (s *platformService) GetProductsCount(ctx context.Context) (int, error) {
-	logger := s.logger.Named("GetProductsCount").WithContext(ctx)
-
-	output, err := s.apis.Platform.VerifySession(ctx)
+// verifyStoreScopes returns ReauthorizeRequiredError if store has to grant scopes
+// it hasn't granted yet, e.g. after the app's scopes were extended.
+// Unknown scopes are refreshed first, as they aren't missing but not stored yet.
+func (s *platformService) verifyStoreScopes(ctx context.Context, store *entity.Store) error {
+	err := s.refreshStoreScopes(ctx, store)
 	if err != nil {
-		logger.Error("failed to verify session", "err", err)
-		return 0, fmt.Errorf("failed to verify session: %w", err)
+		return err
 	}
-	if !output.IsVerified {
-		logger.Info("invalid session")
-		return 0, errors.New("invalid session")
+	// Scopes of store without access token are known once session token is exchanged
+	if store.Scopes == "" || s.hasRequiredScopes(store) {
+		return nil
 	}
 
-	store, err := s.storages.Store.Get(ctx, output.StoreName)
+	app, err := s.storeApp(store)
 	if err != nil {
-		logger.Error("failed to get store from storage", "err", err)
-		return 0, fmt.Errorf("failed to get store from storage: %w", err)
+		return err
 	}
-	if store == nil {
-		logger.Info("store not found, creating new store", "store_name", output.StoreName)
-		store, err = s.storages.Store.Create(ctx, &entity.Store{
-			Name:      output.StoreName,
-			Installed: true,
-		})
-		if err != nil {
-			logger.Error("failed to create store", "err", err)
-			return 0, fmt.Errorf("failed to create store: %w", err)
-		}
-		logger.Info("successfully created store", "storeId", store.ID, "storeName", store.Name)
+	redirectURL, err := s.apis.Platform.WithApp(ctx, app).SignAppURL(store.Name)
+	if err != nil {
+		return fmt.Errorf("failed to sign app url: %w", err)
 	}
+	return &ReauthorizeRequiredError{RedirectURL: redirectURL}
+}
 
-	// Get session token from context for API calls
-	sessionToken := getSessionTokenFromContext(ctx)
-	if sessionToken == "" {
-		logger.Error("missing session token for API call")
-		return 0, fmt.Errorf("missing session token for API call")
+// saveOfflineSession saves store's offline access token as its offline session,
+// so requests made in offline mode use it instead of exchanging session tokens.
+func (s *platformService) saveOfflineSession(ctx context.Context, store *entity.Store) error {
+	_, err := s.storages.Session.Save(ctx, &entity.Session{
+		SessionID:   entity.OfflineSessionID(store.App, store.Name),
+		StoreID:     store.ID,
+		Shop:        store.Name,
+		AccessToken: store.AccessToken,
+		Scope:       store.Scopes,
+		ExpiresAt:   store.AccessTokenExpiresAt,
+	})
+	return err
+}
+
+// sessionAPI returns PlatformAPI authenticated with online access token of the user or offline access token of the store.
+// Access token is exchanged for the request's session token once and reused until it's about to expire.
+// ErrInsufficientScopes is returned if the session doesn't have the required scope, e.g. the user has no permissions for it.
+func (s *platformService) sessionAPI(ctx context.Context, store *entity.Store, principal *Principal, mode entity.AccessMode, requiredScope string) (PlatformAPI, error) {
+	logger := s.logger.
+		Named("sessionAPI").
+		WithContext(ctx).
+		With("app", store.App, "storeName", store.Name, "mode", mode, "userID", principal.UserID)
+
+	if mode != entity.AccessModeOnline && mode != entity.AccessModeOffline {
+		return nil, fmt.Errorf("unknown access mode %q", mode)
 	}
+	online := mode == entity.AccessModeOnline
+	sessionID := entity.SessionID(mode, store.App, store.Name, principal.UserID)
 
-	count, err := s.apis.Platform.WithSessionToken(ctx, store, sessionToken).GetProductsCount(ctx)
+	session, err := s.storages.Session.Get(ctx, sessionID)
 	if err != nil {
-		logger.Error("failed to get product count", "err", err)
-		return 0, fmt.Errorf("failed to get product count: %w", err)
+		logger.Error("failed to get session from storage", "err", err)
+		return nil, fmt.Errorf("failed to get session from storage: %w", err)
+	}
+	// Session token is exchanged for store with unknown scopes, as the exchanged access token carries them
+	if store.Scopes != "" && session != nil && session.AccessToken != "" && !session.ExpiresWithin(s.config.Shopify.AccessTokenRefreshBefore) {
+		return s.authorizeSession(ctx, store, session, requiredScope)
 	}
 
-	return count, nil
-}
+	exchanged, err := s.api(ctx).ExchangeSessionToken(ctx, store.Name, principal.SessionToken, mode)
+	if err != nil {
+		logger.Error("failed to exchange session token", "err", err)
+		return nil, fmt.Errorf("failed to exchange session token: %w", err)
+	}
 
-// getSessionTokenFromContext extracts the session token from the authorization header
-func getSessionTokenFromContext(ctx context.Context) string {
-	authHeader := ctx.Value("Authorization")
-	if authHeader == nil {
-		return ""
+	session = &entity.Session{
+		SessionID:   sessionID,
+		StoreID:     store.ID,
+		Shop:        store.Name,
+		IsOnline:    online,
+		AccessToken: exchanged.AccessToken,
+		Scope:       exchanged.Scopes,
+		ExpiresAt:   exchanged.ExpiresAt,
+	}
+	if online {
+		session.UserID = principal.UserID
+		session.AssociatedUser = exchanged.AssociatedUser
 	}
 
-	authStr, ok := authHeader.(string)
-	if !ok {
-		return ""
+	// Request can still be made with the exchanged token, it's exchanged again next time
+	_, err = s.storages.Session.Save(ctx, session)
+	if err != nil {
+		logger.Error("failed to save session", "err", err)
 	}
 
-	// Extract token from "Bearer <token>" format
-	parts := strings.Split(authStr, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return ""
+	// Exchanged access token carries scopes granted by store in both access modes
+	err = s.saveStoreScopes(ctx, store, exchanged.Scopes)
+	if err != nil {
+		logger.Error("failed to save store scopes", "err", err)
 	}
 
-	return parts[1]
+	return s.authorizeSession(ctx, store, session, requiredScope)
+}
+
+// authorizeSession returns PlatformAPI authenticated with session's access token
+// if store has granted the app's scopes and the session has the required scope.
+func (s *platformService) authorizeSession(ctx context.Context, store *entity.Store, session *entity.Session, requiredScope string) (PlatformAPI, error) {
+	err := s.verifyStoreScopes(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	if !session.Scopes().Has(requiredScope) {
+		s.logger.
+			Named("authorizeSession").
+			WithContext(ctx).
+			Info("session doesn't have required scope", "sessionID", session.SessionID, "requiredScope", requiredScope)
+		return nil, ErrInsufficientScopes
+	}
+	return s.api(ctx).WithSession(ctx, session), nil
 }
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antflydb/shopify-app-template-go/config"
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+)
+
+const testShop = "shop.myshopify.com"
+
+// fakePlatformAPI grants scopes to exchanged access tokens and to the app's installation.
+type fakePlatformAPI struct {
+	PlatformAPI
+	scopes    string
+	exchanged int
+}
+
+func (f *fakePlatformAPI) WithApp(ctx context.Context, app *entity.App) PlatformAPI {
+	return f
+}
+
+func (f *fakePlatformAPI) WithSession(ctx context.Context, session *entity.Session) PlatformAPI {
+	return f
+}
+
+func (f *fakePlatformAPI) WithTokenSource(ctx context.Context, storeName string, source TokenSource) PlatformAPI {
+	return f
+}
+
+func (f *fakePlatformAPI) ExchangeSessionToken(ctx context.Context, storeName, sessionToken string, mode entity.AccessMode) (*APIExchangeSessionTokenOutput, error) {
+	f.exchanged++
+	return &APIExchangeSessionTokenOutput{AccessToken: "shpat_exchanged", Scopes: f.scopes}, nil
+}
+
+func (f *fakePlatformAPI) GetAccessScopes(ctx context.Context) (string, error) {
+	return f.scopes, nil
+}
+
+func (f *fakePlatformAPI) SignAppURL(storeName string) (string, error) {
+	return "https://app.example.com/?shop=" + storeName, nil
+}
+
+func (f *fakePlatformAPI) GetProductsCount(ctx context.Context) (int, error) {
+	return 1, nil
+}
+
+// fakeStoreStorage keeps stores of the default app by name.
+type fakeStoreStorage struct {
+	StoreStorage
+	stores map[string]*entity.Store
+}
+
+func (f *fakeStoreStorage) Get(ctx context.Context, app, storeName string) (*entity.Store, error) {
+	store, ok := f.stores[storeName]
+	if !ok {
+		return nil, nil
+	}
+	stored := *store
+	return &stored, nil
+}
+
+func (f *fakeStoreStorage) Create(ctx context.Context, store *entity.Store) (*entity.Store, error) {
+	created := *store
+	created.ID = "store-1"
+	f.stores[store.Name] = &created
+	return &created, nil
+}
+
+func (f *fakeStoreStorage) UpdateScopes(ctx context.Context, app, storeName, scopes string) error {
+	f.stores[storeName].Scopes = scopes
+	return nil
+}
+
+// fakeSessionStorage keeps sessions by ID.
+type fakeSessionStorage struct {
+	SessionStorage
+	sessions map[string]*entity.Session
+}
+
+func (f *fakeSessionStorage) Get(ctx context.Context, sessionID string) (*entity.Session, error) {
+	return f.sessions[sessionID], nil
+}
+
+func (f *fakeSessionStorage) Save(ctx context.Context, session *entity.Session) (*entity.Session, error) {
+	f.sessions[session.SessionID] = session
+	return session, nil
+}
+
+func newTestPlatformService(t *testing.T, api PlatformAPI, stores *fakeStoreStorage, sessions *fakeSessionStorage) *platformService {
+	t.Helper()
+
+	apps, err := entity.NewApps(&entity.App{APIKey: "key", Secrets: []string{"secret"}}, "")
+	if err != nil {
+		t.Fatalf("failed to create apps: %v", err)
+	}
+	cfg := &config.Config{}
+	cfg.Shopify.Scopes = "read_products,write_products"
+	cfg.Shopify.ProductsAccessMode = string(entity.AccessModeOffline)
+
+	return NewPlatformService(&Options{
+		Apis:     APIs{Platform: api},
+		Storages: Storages{Store: stores, Session: sessions},
+		Webhooks: NewWebhookRouter(),
+		Apps:     apps,
+		Config:   cfg,
+		Logger:   logging.NewZap("error"),
+	})
+}
+
+func TestProductsAPIStoreScopes(t *testing.T) {
+	offlineSessionID := entity.OfflineSessionID(entity.DefaultAppHandle, testShop)
+	cachedSession := &entity.Session{SessionID: offlineSessionID, Shop: testShop, AccessToken: "shpat_cached", Scope: "write_products"}
+
+	tests := []struct {
+		name          string
+		store         *entity.Store
+		session       *entity.Session
+		grantedScopes string
+		wantExchanged int
+		wantScopes    string
+		wantReauth    bool
+	}{
+		{
+			name:          "new store",
+			grantedScopes: "write_products",
+			wantExchanged: 1,
+			wantScopes:    "write_products",
+		},
+		{
+			name:          "migrated store",
+			store:         &entity.Store{ID: "store-1", App: entity.DefaultAppHandle, Name: testShop, AccessToken: "shpat_offline", Installed: true},
+			session:       cachedSession,
+			grantedScopes: "write_products",
+			wantExchanged: 1,
+			wantScopes:    "write_products",
+		},
+		{
+			name:          "migrated store missing scopes",
+			store:         &entity.Store{ID: "store-1", App: entity.DefaultAppHandle, Name: testShop, AccessToken: "shpat_offline", Installed: true},
+			grantedScopes: "read_products",
+			wantExchanged: 1,
+			wantScopes:    "read_products",
+			wantReauth:    true,
+		},
+		{
+			name:          "store with known scopes",
+			store:         &entity.Store{ID: "store-1", App: entity.DefaultAppHandle, Name: testShop, AccessToken: "shpat_offline", Scopes: "write_products", Installed: true},
+			session:       cachedSession,
+			grantedScopes: "write_products",
+			wantScopes:    "write_products",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakePlatformAPI{scopes: tt.grantedScopes}
+			stores := &fakeStoreStorage{stores: map[string]*entity.Store{}}
+			if tt.store != nil {
+				stores.stores[testShop] = tt.store
+			}
+			sessions := &fakeSessionStorage{sessions: map[string]*entity.Session{}}
+			if tt.session != nil {
+				sessions.sessions[tt.session.SessionID] = tt.session
+			}
+			s := newTestPlatformService(t, api, stores, sessions)
+
+			ctx := ContextWithPrincipal(context.Background(), &Principal{App: entity.DefaultAppHandle, Shop: testShop, SessionToken: "session-token"})
+			_, err := s.GetProductsCount(ctx)
+
+			var reauthorizeErr *ReauthorizeRequiredError
+			if tt.wantReauth {
+				if !errors.As(err, &reauthorizeErr) {
+					t.Fatalf("error = %v, want ReauthorizeRequiredError", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if api.exchanged != tt.wantExchanged {
+				t.Errorf("exchanged %d times, want %d", api.exchanged, tt.wantExchanged)
+			}
+			if got := stores.stores[testShop].Scopes; got != tt.wantScopes {
+				t.Errorf("store scopes = %q, want %q", got, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestVerifyStoreScopesRefreshesUnknownScopes(t *testing.T) {
+	tests := []struct {
+		name          string
+		store         *entity.Store
+		grantedScopes string
+		wantScopes    string
+		wantReauth    bool
+	}{
+		{
+			name:          "granted scopes",
+			store:         &entity.Store{App: entity.DefaultAppHandle, Name: testShop, AccessToken: "shpat_offline", Installed: true},
+			grantedScopes: "write_products",
+			wantScopes:    "write_products",
+		},
+		{
+			name:          "missing scopes",
+			store:         &entity.Store{App: entity.DefaultAppHandle, Name: testShop, AccessToken: "shpat_offline", Installed: true},
+			grantedScopes: "read_products",
+			wantScopes:    "read_products",
+			wantReauth:    true,
+		},
+		{
+			name:  "store without access token",
+			store: &entity.Store{App: entity.DefaultAppHandle, Name: testShop, Installed: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stores := &fakeStoreStorage{stores: map[string]*entity.Store{testShop: tt.store}}
+			s := newTestPlatformService(t, &fakePlatformAPI{scopes: tt.grantedScopes}, stores, nil)
+
+			store, _ := stores.Get(context.Background(), entity.DefaultAppHandle, testShop)
+			err := s.verifyStoreScopes(context.Background(), store)
+
+			var reauthorizeErr *ReauthorizeRequiredError
+			if tt.wantReauth {
+				if !errors.As(err, &reauthorizeErr) {
+					t.Fatalf("error = %v, want ReauthorizeRequiredError", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := stores.stores[testShop].Scopes; got != tt.wantScopes {
+				t.Errorf("store scopes = %q, want %q", got, tt.wantScopes)
+			}
+		})
+	}
+}
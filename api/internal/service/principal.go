@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// Principal is the user authenticated by App Bridge session token of the request.
+type Principal struct {
+	// App is handle of the app the session token is issued for.
+	App string
+	// Shop is the store the session token is issued for.
+	Shop string
+	// UserID is ID of the staff member using the app.
+	UserID string
+	// SessionID is ID of the user's Shopify admin session.
+	SessionID string
+	// ExpiresAt is when the session token expires.
+	ExpiresAt time.Time
+	// SessionToken is the verified session token, it's exchanged for access tokens.
+	SessionToken string `json:"-"`
+}
+
+// principalContextKey is used to store principal in request context.
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns context carrying the authenticated principal.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns principal authenticated for the request, or nil if request isn't authenticated.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
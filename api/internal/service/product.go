@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/pkg/errs"
+)
+
+const (
+	// maxProductOptions is the number of options a product can have.
+	maxProductOptions = 3
+	// maxProductTitleLength is the number of characters product title can have.
+	maxProductTitleLength = 255
+	// DefaultProductsLimit is the number of products listed if limit is not given.
+	DefaultProductsLimit = 50
+	// MaxProductsLimit is the number of products which can be listed at once.
+	MaxProductsLimit = 250
+)
+
+var priceRegexp = regexp.MustCompile(`^\d+(\.\d+)?$`)
+
+// ListProductsOptions are options of listing products.
+type ListProductsOptions struct {
+	// Query filters products with platform's search syntax, e.g. status:active vendor:Acme.
+	Query string
+	// Limit is the number of products to list, DefaultProductsLimit if zero.
+	Limit int
+}
+
+// ProductInput is a product to create, or to replace an existing product with.
+// Options and variants of existing product are replaced if they are given, and its images always are.
+// Metafields are only added or updated.
+type ProductInput struct {
+	Title           string `json:"title"`
+	DescriptionHTML string `json:"description_html"`
+	Vendor          string `json:"vendor"`
+	ProductType     string `json:"product_type"`
+	// Status is one of entity.ProductStatus*, products are active by default.
+	Status     string                `json:"status"`
+	Tags       []string              `json:"tags"`
+	Options    []ProductOptionInput  `json:"options"`
+	Variants   []ProductVariantInput `json:"variants"`
+	Images     []ProductImageInput   `json:"images"`
+	Metafields []MetafieldInput      `json:"metafields"`
+}
+
+// ProductOptionInput is an option of product with all its values.
+type ProductOptionInput struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// ProductVariantInput is a variant of product. Existing variant is kept if its ID is given.
+type ProductVariantInput struct {
+	ID             string `json:"id"`
+	SKU            string `json:"sku"`
+	Barcode        string `json:"barcode"`
+	Price          string `json:"price"`
+	CompareAtPrice string `json:"compare_at_price"`
+	// Options maps name of every product option to the variant's value.
+	Options map[string]string `json:"options"`
+}
+
+// ProductImageInput is an image of product, either existing one given by ID or a new one downloaded from URL.
+type ProductImageInput struct {
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	AltText string `json:"alt_text"`
+}
+
+// MetafieldInput is a metafield to add to product or update.
+type MetafieldInput struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+}
+
+// Validate returns ValidationError if input is not a valid product.
+func (i *ProductInput) Validate() error {
+	fields := make(map[string]string)
+
+	title := strings.TrimSpace(i.Title)
+	switch {
+	case title == "":
+		fields["title"] = "is required"
+	case utf8.RuneCountInString(title) > maxProductTitleLength:
+		fields["title"] = fmt.Sprintf("must be at most %d characters", maxProductTitleLength)
+	}
+
+	switch i.Status {
+	case "", entity.ProductStatusActive, entity.ProductStatusDraft, entity.ProductStatusArchived:
+	default:
+		fields["status"] = fmt.Sprintf("must be one of %s, %s, %s", entity.ProductStatusActive, entity.ProductStatusDraft, entity.ProductStatusArchived)
+	}
+
+	for n, tag := range i.Tags {
+		if strings.TrimSpace(tag) == "" {
+			fields[fmt.Sprintf("tags[%d]", n)] = "must not be empty"
+		}
+	}
+
+	if len(i.Options) > maxProductOptions {
+		fields["options"] = fmt.Sprintf("must be at most %d", maxProductOptions)
+	}
+	optionValues := make(map[string][]string, len(i.Options))
+	for n, option := range i.Options {
+		field := fmt.Sprintf("options[%d]", n)
+		if _, ok := optionValues[option.Name]; ok {
+			fields[field+".name"] = "must be unique"
+		}
+		if strings.TrimSpace(option.Name) == "" {
+			fields[field+".name"] = "is required"
+		}
+		if len(option.Values) == 0 {
+			fields[field+".values"] = "are required"
+		}
+		for m, value := range option.Values {
+			if strings.TrimSpace(value) == "" || slices.Index(option.Values, value) != m {
+				fields[fmt.Sprintf("%s.values[%d]", field, m)] = "must be unique and not empty"
+			}
+		}
+		optionValues[option.Name] = option.Values
+	}
+
+	// Product without options has a single variant
+	if len(i.Options) == 0 && len(i.Variants) > 1 {
+		fields["variants"] = "product without options must have at most 1 variant"
+	}
+	combinations := make(map[string]bool, len(i.Variants))
+	for n, variant := range i.Variants {
+		field := fmt.Sprintf("variants[%d]", n)
+		if !priceRegexp.MatchString(variant.Price) {
+			fields[field+".price"] = "must be a non-negative decimal number"
+		}
+		if variant.CompareAtPrice != "" && !priceRegexp.MatchString(variant.CompareAtPrice) {
+			fields[field+".compare_at_price"] = "must be a non-negative decimal number"
+		}
+
+		for name, value := range variant.Options {
+			if !slices.Contains(optionValues[name], value) {
+				fields[field+".options."+name] = "must be a value of the product's option"
+			}
+		}
+		if len(variant.Options) != len(i.Options) {
+			fields[field+".options"] = "must have a value of every product option"
+		}
+
+		var combination []string
+		for _, option := range i.Options {
+			combination = append(combination, variant.Options[option.Name])
+		}
+		key := strings.Join(combination, "\x00")
+		if combinations[key] {
+			fields[field+".options"] = "must differ from options of other variants"
+		}
+		combinations[key] = true
+	}
+
+	for n, image := range i.Images {
+		field := fmt.Sprintf("images[%d]", n)
+		if image.ID != "" {
+			continue
+		}
+		u, err := url.Parse(image.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			fields[field+".url"] = "must be an https URL"
+		}
+	}
+
+	metafields := make(map[string]bool, len(i.Metafields))
+	for n, metafield := range i.Metafields {
+		field := fmt.Sprintf("metafields[%d]", n)
+		if metafield.Namespace == "" {
+			fields[field+".namespace"] = "is required"
+		}
+		if metafield.Key == "" {
+			fields[field+".key"] = "is required"
+		}
+		if metafield.Type == "" {
+			fields[field+".type"] = "is required"
+		}
+		if metafield.Value == "" {
+			fields[field+".value"] = "is required"
+		}
+		if metafields[metafield.Namespace+"."+metafield.Key] {
+			fields[field+".key"] = "must be unique within namespace"
+		}
+		metafields[metafield.Namespace+"."+metafield.Key] = true
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func (s *platformService) GetProductsCount(ctx context.Context) (int, error) {
+	logger := s.logger.Named("GetProductsCount").WithContext(ctx)
+
+	api, err := s.productsAPI(ctx, "read_products")
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := api.GetProductsCount(ctx)
+	if err != nil {
+		logger.Error("failed to get product count", "err", err)
+		return 0, fmt.Errorf("failed to get product count: %w", err)
+	}
+
+	return count, nil
+}
+
+func (s *platformService) ListProducts(ctx context.Context, opts ListProductsOptions) ([]entity.Product, error) {
+	logger := s.logger.Named("ListProducts").WithContext(ctx).With("opts", opts)
+
+	limit := opts.Limit
+	if limit == 0 {
+		limit = DefaultProductsLimit
+	}
+	if limit < 0 || limit > MaxProductsLimit {
+		return nil, &ValidationError{Fields: map[string]string{
+			"limit": fmt.Sprintf("must be between 1 and %d", MaxProductsLimit),
+		}}
+	}
+
+	api, err := s.productsAPI(ctx, "read_products")
+	if err != nil {
+		return nil, err
+	}
+
+	// Only as many products as needed are requested, so the query costs no more than that
+	products := make([]entity.Product, 0, limit)
+	for product, err := range api.ListProducts(ctx, opts.Query, limit) {
+		if err != nil {
+			logger.Error("failed to list products", "err", err)
+			return nil, fmt.Errorf("failed to list products: %w", err)
+		}
+		products = append(products, product)
+		if len(products) == limit {
+			break
+		}
+	}
+
+	return products, nil
+}
+
+func (s *platformService) GetProduct(ctx context.Context, productID string) (*entity.Product, error) {
+	logger := s.logger.Named("GetProduct").WithContext(ctx).With("productID", productID)
+
+	api, err := s.productsAPI(ctx, "read_products")
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := api.GetProduct(ctx, productID)
+	if err != nil {
+		logger.Error("failed to get product", "err", err)
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		logger.Info("product is not found")
+		return nil, ErrProductNotFound
+	}
+
+	return product, nil
+}
+
+func (s *platformService) CreateProduct(ctx context.Context, input *ProductInput) (*entity.Product, error) {
+	logger := s.logger.Named("CreateProduct").WithContext(ctx)
+
+	err := input.Validate()
+	if err != nil {
+		logger.Info("product is invalid", "err", err)
+		return nil, err
+	}
+
+	api, err := s.productsAPI(ctx, "write_products")
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := api.CreateProduct(ctx, input)
+	if err != nil {
+		logger.Error("failed to create product", "err", err)
+		return nil, fmt.Errorf("failed to create product: %w", err)
+	}
+	logger.Info("created product", "productID", product.ID)
+
+	return product, nil
+}
+
+func (s *platformService) UpdateProduct(ctx context.Context, productID string, input *ProductInput) (*entity.Product, error) {
+	logger := s.logger.Named("UpdateProduct").WithContext(ctx).With("productID", productID)
+
+	err := input.Validate()
+	if err != nil {
+		logger.Info("product is invalid", "err", err)
+		return nil, err
+	}
+
+	api, err := s.productsAPI(ctx, "write_products")
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := api.UpdateProduct(ctx, productID, input)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return nil, err
+		}
+		logger.Error("failed to update product", "err", err)
+		return nil, fmt.Errorf("failed to update product: %w", err)
+	}
+	logger.Info("updated product")
+
+	return product, nil
+}
+
+func (s *platformService) DeleteProduct(ctx context.Context, productID string) error {
+	logger := s.logger.Named("DeleteProduct").WithContext(ctx).With("productID", productID)
+
+	api, err := s.productsAPI(ctx, "write_products")
+	if err != nil {
+		return err
+	}
+
+	err = api.DeleteProduct(ctx, productID)
+	if err != nil {
+		if errs.IsExpected(err) {
+			logger.Info(err.Error())
+			return err
+		}
+		logger.Error("failed to delete product", "err", err)
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	logger.Info("deleted product")
+
+	return nil
+}
+
+// handleProductWebhook handles products/create and products/update webhooks.
+// Products are always read from platform, so changes are only logged.
+func (s *platformService) handleProductWebhook(ctx context.Context, webhook *Webhook, payload *entity.ProductWebhookPayload) error {
+	s.logger.
+		Named("handleProductWebhook").
+		WithContext(ctx).
+		With("app", webhook.App, "storeName", webhook.StoreName, "topic", webhook.Topic).
+		Info("product is changed", "productID", payload.AdminGraphqlID, "title", payload.Title, "status", payload.Status)
+	return nil
+}
+
+// productsAPI returns PlatformAPI managing products of the authenticated user's store,
+// authenticated in the configured products access mode with the required scope.
+func (s *platformService) productsAPI(ctx context.Context, requiredScope string) (PlatformAPI, error) {
+	logger := s.logger.Named("productsAPI").WithContext(ctx)
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		logger.Info("request is not authenticated")
+		return nil, ErrInvalidSessionToken
+	}
+
+	app := s.app(ctx)
+	store, err := s.storages.Store.Get(ctx, app.Handle, principal.Shop)
+	if err != nil {
+		logger.Error("failed to get store from storage", "err", err)
+		return nil, fmt.Errorf("failed to get store from storage: %w", err)
+	}
+	if store == nil {
+		logger.Info("store not found, creating new store", "store_name", principal.Shop)
+		store, err = s.storages.Store.Create(ctx, &entity.Store{
+			App:       app.Handle,
+			Name:      principal.Shop,
+			Installed: true,
+		})
+		if err != nil {
+			logger.Error("failed to create store", "err", err)
+			return nil, fmt.Errorf("failed to create store: %w", err)
+		}
+		logger.Info("successfully created store", "storeId", store.ID, "storeName", store.Name)
+	}
+
+	// Store's scopes are verified once session token is exchanged, as they are unknown for a new store
+	api, err := s.sessionAPI(ctx, store, principal, entity.AccessMode(s.config.Shopify.ProductsAccessMode), requiredScope)
+	if err != nil {
+		var reauthorizeErr *ReauthorizeRequiredError
+		if errs.IsExpected(err) || errors.As(err, &reauthorizeErr) {
+			logger.Info(err.Error())
+			return nil, err
+		}
+		logger.Error("failed to get session api", "err", err)
+		return nil, fmt.Errorf("failed to get session api: %w", err)
+	}
+
+	return api, nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// validProductInput returns input of a product with two options and a variant of every combination of their values.
+func validProductInput() *ProductInput {
+	return &ProductInput{
+		Title:  "T-shirt",
+		Status: "ACTIVE",
+		Tags:   []string{"summer"},
+		Options: []ProductOptionInput{
+			{Name: "Size", Values: []string{"S", "M"}},
+			{Name: "Color", Values: []string{"Red"}},
+		},
+		Variants: []ProductVariantInput{
+			{Price: "10.00", Options: map[string]string{"Size": "S", "Color": "Red"}},
+			{Price: "12", CompareAtPrice: "15.50", Options: map[string]string{"Size": "M", "Color": "Red"}},
+		},
+		Images: []ProductImageInput{
+			{URL: "https://cdn.example.com/t-shirt.png"},
+			{ID: "gid://shopify/MediaImage/1"},
+		},
+		Metafields: []MetafieldInput{
+			{Namespace: "custom", Key: "material", Type: "single_line_text_field", Value: "cotton"},
+			{Namespace: "other", Key: "material", Type: "single_line_text_field", Value: "wool"},
+		},
+	}
+}
+
+func TestProductInputValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(i *ProductInput)
+		// wantFields are fields reported as invalid, input is valid if they are empty
+		wantFields []string
+	}{
+		{
+			name:   "valid product",
+			modify: func(i *ProductInput) {},
+		},
+		{
+			name: "product without options with a single variant",
+			modify: func(i *ProductInput) {
+				i.Options = nil
+				i.Variants = []ProductVariantInput{{Price: "0"}}
+			},
+		},
+		{
+			name: "product without options and variants",
+			modify: func(i *ProductInput) {
+				i.Options = nil
+				i.Variants = nil
+			},
+		},
+		{
+			name: "product without options with multiple variants",
+			modify: func(i *ProductInput) {
+				i.Options = nil
+				i.Variants = []ProductVariantInput{{Price: "1"}, {Price: "2", SKU: "other"}}
+			},
+			wantFields: []string{"variants", "variants[1].options"},
+		},
+		{
+			name: "missing title",
+			modify: func(i *ProductInput) {
+				i.Title = "  "
+			},
+			wantFields: []string{"title"},
+		},
+		{
+			name: "unknown status",
+			modify: func(i *ProductInput) {
+				i.Status = "active"
+			},
+			wantFields: []string{"status"},
+		},
+		{
+			name: "too many options",
+			modify: func(i *ProductInput) {
+				i.Options = append(i.Options,
+					ProductOptionInput{Name: "Material", Values: []string{"Cotton"}},
+					ProductOptionInput{Name: "Fit", Values: []string{"Slim"}},
+				)
+				i.Variants = nil
+			},
+			wantFields: []string{"options"},
+		},
+		{
+			name: "duplicate option name",
+			modify: func(i *ProductInput) {
+				i.Options[1].Name = "Size"
+				i.Variants = nil
+			},
+			wantFields: []string{"options[1].name"},
+		},
+		{
+			name: "option without values",
+			modify: func(i *ProductInput) {
+				i.Options[1].Values = nil
+				i.Variants = nil
+			},
+			wantFields: []string{"options[1].values"},
+		},
+		{
+			name: "duplicate option value",
+			modify: func(i *ProductInput) {
+				i.Options[0].Values = []string{"S", "M", "S"}
+			},
+			wantFields: []string{"options[0].values[2]"},
+		},
+		{
+			name: "variant with unknown option value",
+			modify: func(i *ProductInput) {
+				i.Variants[1].Options["Size"] = "XL"
+			},
+			wantFields: []string{"variants[1].options.Size"},
+		},
+		{
+			name: "variant with unknown option",
+			modify: func(i *ProductInput) {
+				i.Variants[1].Options = map[string]string{"Size": "M", "Material": "Cotton"}
+			},
+			wantFields: []string{"variants[1].options.Material"},
+		},
+		{
+			name: "variant without value of every option",
+			modify: func(i *ProductInput) {
+				delete(i.Variants[1].Options, "Color")
+			},
+			wantFields: []string{"variants[1].options"},
+		},
+		{
+			name: "variants with the same options",
+			modify: func(i *ProductInput) {
+				i.Variants[1].Options["Size"] = "S"
+			},
+			wantFields: []string{"variants[1].options"},
+		},
+		{
+			name: "missing price",
+			modify: func(i *ProductInput) {
+				i.Variants[0].Price = ""
+			},
+			wantFields: []string{"variants[0].price"},
+		},
+		{
+			name: "invalid prices",
+			modify: func(i *ProductInput) {
+				i.Variants[0].Price = "-1"
+				i.Variants[1].Price = "1,50"
+				i.Variants[1].CompareAtPrice = "1."
+			},
+			wantFields: []string{"variants[0].price", "variants[1].price", "variants[1].compare_at_price"},
+		},
+		{
+			name: "image URL is not https",
+			modify: func(i *ProductInput) {
+				i.Images[0].URL = "http://cdn.example.com/t-shirt.png"
+			},
+			wantFields: []string{"images[0].url"},
+		},
+		{
+			name: "duplicate metafield key within namespace",
+			modify: func(i *ProductInput) {
+				i.Metafields[1].Namespace = "custom"
+			},
+			wantFields: []string{"metafields[1].key"},
+		},
+		{
+			name: "incomplete metafield",
+			modify: func(i *ProductInput) {
+				i.Metafields[0] = MetafieldInput{Key: "material"}
+			},
+			wantFields: []string{"metafields[0].namespace", "metafields[0].type", "metafields[0].value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := validProductInput()
+			tt.modify(input)
+
+			err := input.Validate()
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			fields := slices.Sorted(maps.Keys(validationErr.Fields))
+			wantFields := slices.Sorted(slices.Values(tt.wantFields))
+			if !slices.Equal(fields, wantFields) {
+				t.Errorf("invalid fields = %v, want %v", validationErr.Fields, wantFields)
+			}
+		})
+	}
+}
@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/antflydb/shopify-app-template-go/config"
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/pkg/errs"
 	"github.com/antflydb/shopify-app-template-go/pkg/logging"
 )
@@ -11,40 +16,108 @@ import (
 // Services contains all available services.
 type Services struct {
 	Platform PlatformService
+	Webhook  WebhookService
 }
 
 // Options provides options for creating a new service instance.
 type Options struct {
 	Apis     APIs
 	Storages Storages
+	Webhooks *WebhookRouter
+	Apps     *entity.Apps
 	Config   *config.Config
 	Logger   logging.Logger
 }
 
 // PlatformService provides business logic related to shop platformService.
 type PlatformService interface {
-	Handle(ctx context.Context, storeName, installationURL string) (string, error)
+	// Handle handles a call to the app's URL and returns URL to redirect user to.
+	// If authorization is started, the returned output contains nonce to bind to user's browser.
+	Handle(ctx context.Context, storeName, installationURL string) (*HandleOutput, error)
 	// HandleRedirect handles an oauth2 redirect call for a platform integration.
 	HandleRedirect(ctx context.Context, opts ServiceHandleRedirectOptions) error
 	// HandleUninstall is called when user wants to uninstall the app from a platform.
 	// In this case we need to delete all records about their store from database.
 	HandleUninstall(ctx context.Context, storeName string) error
+	// VerifySessionToken verifies App Bridge session token and returns the principal it authenticates.
+	VerifySessionToken(ctx context.Context, sessionToken string) (*Principal, error)
 	// GetProductsCount returns number of products in store.
 	GetProductsCount(ctx context.Context) (int, error)
-	// CreateProducts creates random products in store.
-	CreateProducts(ctx context.Context) error
+	// ListProducts returns products of store matching the options.
+	ListProducts(ctx context.Context, opts ListProductsOptions) ([]entity.Product, error)
+	// GetProduct returns product of store with its options, variants, images and metafields.
+	GetProduct(ctx context.Context, productID string) (*entity.Product, error)
+	// CreateProduct validates input and creates product in store.
+	CreateProduct(ctx context.Context, input *ProductInput) (*entity.Product, error)
+	// UpdateProduct validates input and replaces product in store with it.
+	UpdateProduct(ctx context.Context, productID string, input *ProductInput) (*entity.Product, error)
+	// DeleteProduct deletes product from store.
+	DeleteProduct(ctx context.Context, productID string) error
+	// ReconcileWebhookSubscriptions makes webhook subscriptions of the session's store match the registered topics.
+	ReconcileWebhookSubscriptions(ctx context.Context) (*ReconcileWebhookSubscriptionsOutput, error)
+	// ReconcileAllWebhookSubscriptions reconciles webhook subscriptions of all installed stores.
+	ReconcileAllWebhookSubscriptions(ctx context.Context) error
+	// RunBulkQuery starts bulk query in the store.
+	RunBulkQuery(ctx context.Context, store *entity.Store, query string) (*entity.BulkOperation, error)
+	// RunBulkMutation starts bulk mutation in the store, run once for every variables.
+	RunBulkMutation(ctx context.Context, store *entity.Store, mutation string, variables []map[string]any) (*entity.BulkOperation, error)
+	// WaitBulkOperation waits until bulk operation of the store finishes and returns it.
+	// Operation is polled until it's finished or bulk_operations/finish webhook is received for it.
+	WaitBulkOperation(ctx context.Context, store *entity.Store, operationID string) (*entity.BulkOperation, error)
+	// BulkOperationResults returns iterator over top-level objects of completed bulk operation with their nested objects.
+	BulkOperationResults(ctx context.Context, store *entity.Store, operation *entity.BulkOperation) iter.Seq2[*entity.BulkObject, error]
+	// HandleBulkOperationsFinish wakes up waiters of the finished bulk operation.
+	HandleBulkOperationsFinish(ctx context.Context, webhook *Webhook, payload *entity.BulkOperationWebhookPayload) error
+	// HandleCustomersDataRequest records customer's request to view their stored data.
+	HandleCustomersDataRequest(ctx context.Context, webhook *Webhook, payload *entity.CustomersDataRequestPayload) error
+	// HandleCustomersRedact erases all data stored about the customer.
+	HandleCustomersRedact(ctx context.Context, webhook *Webhook, payload *entity.CustomersRedactPayload) error
+	// HandleShopRedact permanently erases all data stored about the store.
+	// Shopify sends it 48 hours after the app was uninstalled.
+	HandleShopRedact(ctx context.Context, webhook *Webhook, payload *entity.ShopRedactPayload) error
 }
 
-const (
-	DEFAULT_PRODUCT_COUNT = 5
-)
+// WebhookService handles webhooks received from platform.
+type WebhookService interface {
+	// HandleWebhook queues webhook for processing by the handler registered for its topic.
+	// Repeated deliveries of already processed webhook are acknowledged without handling.
+	HandleWebhook(ctx context.Context, webhook *Webhook) error
+	// PurgeProcessedWebhooks forgets processed webhooks older than deduplication TTL.
+	PurgeProcessedWebhooks(ctx context.Context) error
+}
 
 var (
 	// ErrHandleRedirectStoreNotFound is returned when store is not found.
 	ErrHandleRedirectStoreNotFound = errs.New("store is not found")
 
+	// ErrHandleRedirectNonceExpired is returned when authorization request was not started or has expired.
+	ErrHandleRedirectNonceExpired = errs.New("authorization request has expired")
+
+	// ErrInvalidSessionToken is returned when request's session token is missing or invalid.
+	ErrInvalidSessionToken = errs.New("invalid session token")
+
+	// ErrInsufficientScopes is returned when action requires access scope which is not granted,
+	// e.g. the user making the request doesn't have permissions for it.
+	ErrInsufficientScopes = errs.New("insufficient access scopes")
+
 	// ErrHandleUninstallStoreNotFound is returned when store is not found.
 	ErrHandleUninstallStoreNotFound = errs.New("store is not found")
+
+	// ErrRefreshTokenExpired is returned when store's expiring access token can't be refreshed anymore,
+	// so the store has to be authorized again.
+	ErrRefreshTokenExpired = errs.New("refresh token has expired")
+
+	// ErrReconcileWebhookSubscriptionsStoreNotInstalled is returned when app is not installed in store.
+	ErrReconcileWebhookSubscriptionsStoreNotInstalled = errs.New("store is not installed")
+
+	// ErrBulkOperationNotFound is returned when bulk operation doesn't exist in store.
+	ErrBulkOperationNotFound = errs.New("bulk operation is not found")
+
+	// ErrBulkOperationNotCompleted is returned when results of bulk operation which hasn't completed are requested.
+	ErrBulkOperationNotCompleted = errs.New("bulk operation is not completed")
+
+	// ErrProductNotFound is returned when product doesn't exist in store.
+	ErrProductNotFound = errs.New("product is not found")
 )
 
 type ServiceHandlerOptions struct {
@@ -57,7 +130,44 @@ type ServiceHandleInstallOptions struct {
 	InstallationURL string
 }
 
+// ReauthorizeRequiredError is returned when store has to go through authorization again,
+// e.g. because the app requires access scopes the store hasn't granted yet.
+type ReauthorizeRequiredError struct {
+	// RedirectURL is the URL of the app's entry point to send user to.
+	RedirectURL string
+}
+
+func (e *ReauthorizeRequiredError) Error() string {
+	return "store has to be authorized again"
+}
+
+// ValidationError is returned when input is invalid.
+type ValidationError struct {
+	// Fields maps path of every invalid field to the reason, e.g. variants[0].price.
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := slices.Sorted(maps.Keys(e.Fields))
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field+": "+e.Fields[field])
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
+type HandleOutput struct {
+	RedirectURL string
+	Nonce       string
+}
+
 type ServiceHandleRedirectOptions struct {
 	StoreName     string
 	RedirectedURL string
 }
+
+type ReconcileWebhookSubscriptionsOutput struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Deleted []string `json:"deleted"`
+}
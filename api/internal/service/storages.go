@@ -2,31 +2,92 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/antflydb/shopify-app-template-go/internal/entity"
 )
 
 // Storages contains all available storages.
 type Storages struct {
-	Store StoreStorage
+	Store             StoreStorage
+	Session           SessionStorage
+	ComplianceRequest ComplianceRequestStorage
+	ProcessedWebhook  ProcessedWebhookStorage
+	WebhookJob        WebhookJobStorage
 }
 
 type StoreStorage interface {
-	// Get is used to retrieve store from storage by its name.
-	Get(ctx context.Context, storeName string) (*entity.Store, error)
+	// Get is used to retrieve store of the app from storage by its name.
+	Get(ctx context.Context, app, storeName string) (*entity.Store, error)
+	// ListInstalled is used to retrieve all stores with installed app, of all apps.
+	ListInstalled(ctx context.Context) ([]*entity.Store, error)
 	// Create is used to create new store.
 	Create(ctx context.Context, store *entity.Store) (*entity.Store, error)
 	// Update is used to update store.
 	Update(ctx context.Context, store *entity.Store) (*entity.Store, error)
-	// Delete is used to delete store.
-	Delete(ctx context.Context, storeName string) error
+	// ConsumeNonce is used to clear store's nonce only if it's still the given one.
+	// It reports whether the nonce was cleared, so a nonce can be consumed only once.
+	ConsumeNonce(ctx context.Context, app, storeName, nonce string) (bool, error)
+	// UpdateScopes is used to update scopes granted by store without changing its access token,
+	// which might be refreshed concurrently.
+	UpdateScopes(ctx context.Context, app, storeName, scopes string) error
+	// Delete is used to delete store of the app.
+	Delete(ctx context.Context, app, storeName string) error
+	// Erase is used to permanently remove store of the app and all its related records, including deleted ones.
+	Erase(ctx context.Context, app, storeName string) error
+}
+
+type ComplianceRequestStorage interface {
+	// Create is used to record new compliance request.
+	Create(ctx context.Context, request *entity.ComplianceRequest) (*entity.ComplianceRequest, error)
+	// Update is used to update compliance request's processing outcome.
+	Update(ctx context.Context, request *entity.ComplianceRequest) (*entity.ComplianceRequest, error)
+	// RedactCustomer is used to erase payloads of all requests of the app related to the customer.
+	RedactCustomer(ctx context.Context, app, storeName string, customerID int64) error
+	// RedactStore is used to erase payloads of all requests of the app related to the store.
+	RedactStore(ctx context.Context, app, storeName string) error
 }
 
 type SessionStorage interface {
 	// Get is used to retrieve session from storage by its ID.
 	Get(ctx context.Context, sessionID string) (*entity.Session, error)
+	// ListByStore is used to retrieve all sessions of the store, both offline and online ones.
+	ListByStore(ctx context.Context, storeID string) ([]*entity.Session, error)
 	// Create is used to create new session.
 	Create(ctx context.Context, session *entity.Session) (*entity.Session, error)
+	// Update is used to update existing session.
+	Update(ctx context.Context, session *entity.Session) (*entity.Session, error)
+	// Save is used to create new session or replace existing one with the same ID.
+	Save(ctx context.Context, session *entity.Session) (*entity.Session, error)
 	// Delete is used to delete session.
 	Delete(ctx context.Context, sessionID string) error
+	// DeleteByStore is used to delete all sessions of the store.
+	// It returns number of deleted sessions.
+	DeleteByStore(ctx context.Context, storeID string) (int64, error)
+}
+
+type ProcessedWebhookStorage interface {
+	// Claim is used to record webhook as processed.
+	// It returns false if webhook with the same ID is already recorded.
+	Claim(ctx context.Context, webhook *entity.ProcessedWebhook) (bool, error)
+	// Delete is used to release claimed webhook, so its next delivery is processed again.
+	Delete(ctx context.Context, webhookID string) error
+	// DeleteProcessedBefore is used to delete webhooks processed before the given time.
+	// It returns number of deleted webhooks.
+	DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookJobStorage interface {
+	// Enqueue is used to add webhook job to the queue.
+	Enqueue(ctx context.Context, job *entity.WebhookJob) (*entity.WebhookJob, error)
+	// Claim is used to lock the next due job for processing.
+	// Running jobs locked before staleBefore are considered abandoned and can be claimed again.
+	// It returns nil if there are no jobs to process.
+	Claim(ctx context.Context, staleBefore time.Time) (*entity.WebhookJob, error)
+	// Reschedule is used to unlock failed job and schedule its next attempt at job.RunAt.
+	Reschedule(ctx context.Context, job *entity.WebhookJob) error
+	// Delete is used to remove processed job from the queue.
+	Delete(ctx context.Context, jobID string) error
+	// DeadLetter is used to move job which exhausted all attempts out of the queue.
+	DeadLetter(ctx context.Context, job *entity.WebhookJob) error
 }
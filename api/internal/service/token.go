@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+// storeAPI returns PlatformAPI of the app authenticated with store's offline access token.
+// Expiring access token is refreshed shortly before it expires or when it's rejected.
+func (s *platformService) storeAPI(ctx context.Context, app *entity.App, store *entity.Store) PlatformAPI {
+	return s.apis.Platform.WithApp(ctx, app).WithTokenSource(ctx, store.Name, &storeTokenSource{
+		service: s,
+		app:     app,
+		store:   store,
+	})
+}
+
+// storeTokenSource provides store's offline access token, refreshing it when needed.
+type storeTokenSource struct {
+	service *platformService
+	app     *entity.App
+
+	mu    sync.Mutex
+	store *entity.Store
+}
+
+var _ TokenSource = (*storeTokenSource)(nil)
+
+func (t *storeTokenSource) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.store.AccessTokenExpiresWithin(t.service.config.Shopify.AccessTokenRefreshBefore) {
+		return t.store.AccessToken, nil
+	}
+
+	store, err := t.service.refreshAccessToken(ctx, t.app, t.store.Name, t.store.AccessToken)
+	if err != nil {
+		// Access token which is about to expire is still usable
+		if !t.store.AccessTokenExpiresWithin(0) {
+			return t.store.AccessToken, nil
+		}
+		return "", err
+	}
+	t.store = store
+
+	return t.store.AccessToken, nil
+}
+
+func (t *storeTokenSource) Refresh(ctx context.Context, rejected string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	store, err := t.service.refreshAccessToken(ctx, t.app, t.store.Name, rejected)
+	if err != nil {
+		return "", err
+	}
+	t.store = store
+
+	return t.store.AccessToken, nil
+}
+
+// refreshLock returns lock held while access token of the app's store is refreshed.
+func (s *platformService) refreshLock(app, storeName string) *sync.Mutex {
+	lock, _ := s.refreshLocks.LoadOrStore(app+"/"+storeName, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// refreshAccessToken replaces store's stale access token with a new one obtained with refresh token.
+// Refreshes of the same store are serialized, and the stored access token is returned as is
+// if it was already replaced by a concurrent request, so refresh token is used once.
+func (s *platformService) refreshAccessToken(ctx context.Context, app *entity.App, storeName, staleToken string) (*entity.Store, error) {
+	logger := s.logger.
+		Named("refreshAccessToken").
+		WithContext(ctx).
+		With("app", app.Handle, "storeName", storeName)
+
+	lock := s.refreshLock(app.Handle, storeName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	store, err := s.storages.Store.Get(ctx, app.Handle, storeName)
+	if err != nil {
+		logger.Error("failed to get store from storage", "err", err)
+		return nil, fmt.Errorf("failed to get store from storage: %w", err)
+	}
+	if store == nil {
+		logger.Error("store is not found")
+		return nil, fmt.Errorf("store %s is not found", storeName)
+	}
+
+	if store.AccessToken != staleToken {
+		logger.Debug("access token is already refreshed")
+		return store, nil
+	}
+	if store.RefreshToken == "" {
+		// Access token which doesn't expire can't be refreshed, store has to be authorized again
+		logger.Info("access token can't be refreshed")
+		return store, nil
+	}
+	if store.RefreshTokenExpiresAt != nil && time.Now().After(*store.RefreshTokenExpiresAt) {
+		logger.Info("refresh token has expired", "refreshTokenExpiresAt", store.RefreshTokenExpiresAt)
+		return nil, ErrRefreshTokenExpired
+	}
+
+	refreshed, err := s.apis.Platform.WithApp(ctx, app).RefreshAccessToken(ctx, store.Name, store.RefreshToken)
+	if err != nil {
+		logger.Error("failed to refresh access token", "err", err)
+
+		// Another instance of the app might have used the refresh token first
+		current, getErr := s.storages.Store.Get(ctx, app.Handle, storeName)
+		if getErr == nil && current != nil && current.AccessToken != staleToken {
+			logger.Info("access token was refreshed by another instance")
+			return current, nil
+		}
+		return nil, fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	store.AccessToken = refreshed.AccessToken
+	store.Scopes = refreshed.Scopes
+	store.AccessTokenExpiresAt = refreshed.ExpiresAt
+	store.RefreshTokenExpiresAt = refreshed.RefreshTokenExpiresAt
+	// Refresh token is rotated only if the response contains a new one
+	if refreshed.RefreshToken != "" {
+		store.RefreshToken = refreshed.RefreshToken
+	}
+
+	updatedStore, err := s.storages.Store.Update(ctx, store)
+	if err != nil {
+		logger.Error("failed to save refreshed access token", "err", err)
+		return nil, fmt.Errorf("failed to save refreshed access token: %w", err)
+	}
+	logger.Info("refreshed access token", "accessTokenExpiresAt", updatedStore.AccessTokenExpiresAt)
+
+	// Offline session still has the old access token, which might not be accepted anymore
+	err = s.saveOfflineSession(ctx, updatedStore)
+	if err != nil {
+		logger.Error("failed to save offline session", "err", err)
+	}
+
+	return updatedStore, nil
+}
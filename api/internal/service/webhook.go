@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/config"
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/pkg/errs"
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+)
+
+// Webhook topics handled by the application.
+const (
+	WebhookTopicAppUninstalled = "app/uninstalled"
+	WebhookTopicShopUpdate     = "shop/update"
+	WebhookTopicProductsCreate = "products/create"
+	WebhookTopicProductsUpdate = "products/update"
+
+	WebhookTopicBulkOperationsFinish = "bulk_operations/finish"
+
+	// Mandatory compliance topics can't be subscribed to through Admin API,
+	// they are configured in shopify.app.toml instead.
+	WebhookTopicCustomersDataRequest = "customers/data_request"
+	WebhookTopicCustomersRedact      = "customers/redact"
+	WebhookTopicShopRedact           = "shop/redact"
+)
+
+// complianceWebhookTopics contains mandatory compliance topics.
+var complianceWebhookTopics = map[string]bool{
+	WebhookTopicCustomersDataRequest: true,
+	WebhookTopicCustomersRedact:      true,
+	WebhookTopicShopRedact:           true,
+}
+
+var (
+	// ErrWebhookTopicNotSupported is returned when no handler is registered for webhook topic.
+	ErrWebhookTopicNotSupported = errs.New("webhook topic is not supported")
+	// ErrWebhookInvalidPayload is returned when webhook payload can't be decoded.
+	ErrWebhookInvalidPayload = errs.New("invalid webhook payload")
+)
+
+// Webhook is a verified webhook received from platform.
+// App is handle of the app the webhook is delivered to.
+type Webhook struct {
+	ID         string
+	Topic      string
+	App        string
+	StoreName  string
+	APIVersion string
+	Payload    []byte
+}
+
+// WebhookHandlerFunc handles webhook with payload decoded into T.
+type WebhookHandlerFunc[T any] func(ctx context.Context, webhook *Webhook, payload *T) error
+
+// WebhookRouter dispatches webhooks to handlers registered per topic.
+type WebhookRouter struct {
+	mu       sync.RWMutex
+	handlers map[string]func(ctx context.Context, webhook *Webhook) error
+}
+
+func NewWebhookRouter() *WebhookRouter {
+	return &WebhookRouter{
+		handlers: make(map[string]func(ctx context.Context, webhook *Webhook) error),
+	}
+}
+
+// HandleWebhook registers handler for the given topic.
+// Webhook payload is decoded into T before calling the handler.
+// HandleWebhook panics if handler for the topic is already registered.
+func HandleWebhook[T any](router *WebhookRouter, topic string, handler WebhookHandlerFunc[T]) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	if _, ok := router.handlers[topic]; ok {
+		panic(fmt.Sprintf("webhook handler for topic %q is already registered", topic))
+	}
+
+	router.handlers[topic] = func(ctx context.Context, webhook *Webhook) error {
+		var payload T
+		err := json.Unmarshal(webhook.Payload, &payload)
+		if err != nil {
+			return ErrWebhookInvalidPayload
+		}
+		return handler(ctx, webhook, &payload)
+	}
+}
+
+// Topics returns sorted list of registered topics that can be subscribed to through Admin API.
+// Compliance topics are not included.
+func (r *WebhookRouter) Topics() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	topics := make([]string, 0, len(r.handlers))
+	for topic := range r.handlers {
+		if complianceWebhookTopics[topic] {
+			continue
+		}
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+
+	return topics
+}
+
+// Has reports whether handler for the topic is registered.
+func (r *WebhookRouter) Has(topic string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.handlers[topic]
+	return ok
+}
+
+// Dispatch calls handler registered for webhook topic.
+func (r *WebhookRouter) Dispatch(ctx context.Context, webhook *Webhook) error {
+	r.mu.RLock()
+	handler, ok := r.handlers[webhook.Topic]
+	r.mu.RUnlock()
+
+	if !ok {
+		return ErrWebhookTopicNotSupported
+	}
+
+	return handler(ctx, webhook)
+}
+
+// webhookService implements WebhookService interface.
+type webhookService struct {
+	router   *WebhookRouter
+	storages Storages
+	config   *config.Config
+	logger   logging.Logger
+}
+
+var _ WebhookService = (*webhookService)(nil)
+
+func NewWebhookService(opts *Options) *webhookService {
+	return &webhookService{
+		router:   opts.Webhooks,
+		storages: opts.Storages,
+		config:   opts.Config,
+		logger:   opts.Logger.Named("Webhook"),
+	}
+}
+
+func (s *webhookService) HandleWebhook(ctx context.Context, webhook *Webhook) error {
+	logger := s.logger.
+		Named("HandleWebhook").
+		WithContext(ctx).
+		With("webhookID", webhook.ID, "topic", webhook.Topic, "storeName", webhook.StoreName)
+
+	if !s.router.Has(webhook.Topic) {
+		logger.Info("webhook topic is not supported")
+		return ErrWebhookTopicNotSupported
+	}
+
+	// Shopify delivers webhooks at least once, so claim webhook ID before handling it
+	if webhook.ID != "" {
+		claimed, err := s.storages.ProcessedWebhook.Claim(ctx, &entity.ProcessedWebhook{
+			WebhookID:   webhook.ID,
+			Topic:       webhook.Topic,
+			App:         webhook.App,
+			StoreName:   webhook.StoreName,
+			ProcessedAt: time.Now(),
+		})
+		if err != nil {
+			logger.Error("failed to claim webhook", "err", err)
+			return fmt.Errorf("failed to claim webhook: %w", err)
+		}
+		if !claimed {
+			logger.Info("webhook is already processed")
+			return nil
+		}
+	}
+
+	// Webhook is handled in background, so Shopify gets response within its delivery timeout
+	job, err := s.storages.WebhookJob.Enqueue(ctx, &entity.WebhookJob{
+		WebhookID:  webhook.ID,
+		Topic:      webhook.Topic,
+		App:        webhook.App,
+		StoreName:  webhook.StoreName,
+		APIVersion: webhook.APIVersion,
+		Payload:    string(webhook.Payload),
+	})
+	if err != nil {
+		// Release the claim, so the webhook is handled again when Shopify retries it
+		if webhook.ID != "" {
+			releaseErr := s.storages.ProcessedWebhook.Delete(ctx, webhook.ID)
+			if releaseErr != nil {
+				logger.Error("failed to release webhook claim", "err", releaseErr)
+			}
+		}
+
+		logger.Error("failed to enqueue webhook", "err", err)
+		return fmt.Errorf("failed to enqueue webhook: %w", err)
+	}
+
+	logger.Info("enqueued webhook", "jobID", job.ID)
+	return nil
+}
+
+func (s *webhookService) PurgeProcessedWebhooks(ctx context.Context) error {
+	logger := s.logger.
+		Named("PurgeProcessedWebhooks").
+		WithContext(ctx)
+
+	deleted, err := s.storages.ProcessedWebhook.DeleteProcessedBefore(ctx, time.Now().Add(-s.config.Webhooks.DeduplicationTTL))
+	if err != nil {
+		logger.Error("failed to delete expired processed webhooks", "err", err)
+		return fmt.Errorf("failed to delete expired processed webhooks: %w", err)
+	}
+
+	logger.Info("purged expired processed webhooks", "deleted", deleted)
+	return nil
+}
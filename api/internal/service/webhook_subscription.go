@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+func (s *platformService) ReconcileWebhookSubscriptions(ctx context.Context) (*ReconcileWebhookSubscriptionsOutput, error) {
+	logger := s.logger.Named("ReconcileWebhookSubscriptions").WithContext(ctx)
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		logger.Info("request is not authenticated")
+		return nil, ErrInvalidSessionToken
+	}
+
+	store, err := s.storages.Store.Get(ctx, s.app(ctx).Handle, principal.Shop)
+	if err != nil {
+		logger.Error("failed to get store from storage", "err", err)
+		return nil, fmt.Errorf("failed to get store from storage: %w", err)
+	}
+	if store == nil || !store.Installed {
+		logger.Info("store is not installed", "storeName", principal.Shop)
+		return nil, ErrReconcileWebhookSubscriptionsStoreNotInstalled
+	}
+
+	err = s.verifyStoreScopes(ctx, store)
+	if err != nil {
+		logger.Info("failed to verify store scopes", "err", err)
+		return nil, fmt.Errorf("failed to verify store scopes: %w", err)
+	}
+
+	return s.reconcileWebhookSubscriptions(ctx, store)
+}
+
+func (s *platformService) ReconcileAllWebhookSubscriptions(ctx context.Context) error {
+	logger := s.logger.Named("ReconcileAllWebhookSubscriptions").WithContext(ctx)
+
+	stores, err := s.storages.Store.ListInstalled(ctx)
+	if err != nil {
+		logger.Error("failed to list installed stores", "err", err)
+		return fmt.Errorf("failed to list installed stores: %w", err)
+	}
+
+	// Failure of one store shouldn't prevent others from being reconciled
+	var failures []error
+	for _, store := range stores {
+		_, err = s.reconcileWebhookSubscriptions(ctx, store)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("store %s of app %s: %w", store.Name, store.App, err))
+		}
+	}
+
+	logger.Info("reconciled webhook subscriptions of installed stores", "stores", len(stores), "failed", len(failures))
+	return errors.Join(failures...)
+}
+
+// reconcileWebhookSubscriptions makes store's webhook subscriptions match registered topics and webhook address.
+// Subscriptions with outdated address are updated, missing ones are created,
+// and subscriptions to unknown topics or duplicates are deleted.
+func (s *platformService) reconcileWebhookSubscriptions(ctx context.Context, store *entity.Store) (*ReconcileWebhookSubscriptionsOutput, error) {
+	logger := s.logger.
+		Named("reconcileWebhookSubscriptions").
+		WithContext(ctx).
+		With("app", store.App, "storeName", store.Name)
+
+	app, err := s.storeApp(store)
+	if err != nil {
+		logger.Error("failed to resolve store's app", "err", err)
+		return nil, err
+	}
+	api := s.storeAPI(ctx, app, store)
+	// Webhooks of every app are delivered to its own prefix, so they are verified with its secret
+	address := s.config.App.BaseURL + app.PathPrefix() + "/webhooks"
+	topics := s.webhooks.Topics()
+
+	subscriptions, err := api.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		logger.Error("failed to list webhook subscriptions", "err", err)
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	// Subscriptions have GraphQL enum values of topics, registered topics are matched by theirs
+	enums := make(map[string]string, len(topics))
+	for _, topic := range topics {
+		enums[entity.WebhookTopicEnum(topic)] = topic
+	}
+
+	output := &ReconcileWebhookSubscriptionsOutput{}
+	subscribed := make(map[string]bool, len(topics))
+	for _, subscription := range subscriptions {
+		topic, ok := enums[subscription.Topic]
+		if !ok || subscribed[topic] {
+			err = api.DeleteWebhookSubscription(ctx, subscription.ID)
+			if err != nil {
+				logger.Error("failed to delete webhook subscription", "topic", subscription.Topic, "err", err)
+				return nil, fmt.Errorf("failed to delete %s webhook subscription: %w", subscription.Topic, err)
+			}
+			output.Deleted = append(output.Deleted, subscription.Topic)
+			continue
+		}
+		subscribed[topic] = true
+
+		if subscription.Address != address {
+			err = api.UpdateWebhookSubscription(ctx, subscription.ID, address)
+			if err != nil {
+				logger.Error("failed to update webhook subscription", "topic", topic, "err", err)
+				return nil, fmt.Errorf("failed to update %s webhook subscription: %w", topic, err)
+			}
+			output.Updated = append(output.Updated, topic)
+		}
+	}
+
+	for _, topic := range topics {
+		if subscribed[topic] {
+			continue
+		}
+		_, err = api.CreateWebhookSubscription(ctx, topic, address)
+		if err != nil {
+			logger.Error("failed to create webhook subscription", "topic", topic, "err", err)
+			return nil, fmt.Errorf("failed to create %s webhook subscription: %w", topic, err)
+		}
+		output.Created = append(output.Created, topic)
+	}
+
+	logger.Info("reconciled webhook subscriptions", "created", output.Created, "updated", output.Updated, "deleted", output.Deleted)
+	return output, nil
+}
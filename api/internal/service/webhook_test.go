@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+)
+
+func TestWebhookRouterDispatch(t *testing.T) {
+	router := NewWebhookRouter()
+	var got *entity.ProductWebhookPayload
+	HandleWebhook(router, WebhookTopicProductsUpdate, func(ctx context.Context, webhook *Webhook, payload *entity.ProductWebhookPayload) error {
+		got = payload
+		return nil
+	})
+
+	tests := []struct {
+		name      string
+		webhook   *Webhook
+		wantTitle string
+		wantErr   error
+	}{
+		{
+			name:      "typed payload",
+			webhook:   &Webhook{Topic: WebhookTopicProductsUpdate, Payload: []byte(`{"id":1,"title":"T-shirt"}`)},
+			wantTitle: "T-shirt",
+		},
+		{
+			name:    "invalid payload",
+			webhook: &Webhook{Topic: WebhookTopicProductsUpdate, Payload: []byte(`{"id":"1"}`)},
+			wantErr: ErrWebhookInvalidPayload,
+		},
+		{
+			name:    "unsupported topic",
+			webhook: &Webhook{Topic: WebhookTopicProductsCreate, Payload: []byte(`{}`)},
+			wantErr: ErrWebhookTopicNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			err := router.Dispatch(context.Background(), tt.webhook)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got == nil || got.Title != tt.wantTitle {
+				t.Errorf("payload = %+v, want title %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestPlatformServiceWebhookTopics(t *testing.T) {
+	router := NewWebhookRouter()
+	NewPlatformService(&Options{Webhooks: router, Logger: logging.NewZap("error")})
+
+	// Compliance topics are configured in shopify.app.toml, so they are handled but not subscribed to
+	want := []string{
+		WebhookTopicAppUninstalled,
+		WebhookTopicBulkOperationsFinish,
+		WebhookTopicProductsCreate,
+		WebhookTopicProductsUpdate,
+		WebhookTopicShopUpdate,
+	}
+	if got := router.Topics(); !slices.Equal(got, want) {
+		t.Errorf("topics = %q, want %q", got, want)
+	}
+	for _, topic := range []string{WebhookTopicCustomersDataRequest, WebhookTopicCustomersRedact, WebhookTopicShopRedact} {
+		if !router.Has(topic) {
+			t.Errorf("handler for %s is not registered", topic)
+		}
+	}
+
+	err := router.Dispatch(context.Background(), &Webhook{Topic: WebhookTopicShopUpdate, Payload: []byte(`{"name":"Shop"}`)})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/config"
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/pkg/errs"
+	"github.com/antflydb/shopify-app-template-go/pkg/logging"
+)
+
+// WebhookWorkerPool processes queued webhooks in background.
+// Failed jobs are retried with exponential backoff and moved to dead letters
+// after the configured number of attempts.
+type WebhookWorkerPool struct {
+	router   *WebhookRouter
+	storages Storages
+	apps     *entity.Apps
+	config   *config.Config
+	logger   logging.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWebhookWorkerPool(opts *Options) *WebhookWorkerPool {
+	return &WebhookWorkerPool{
+		router:   opts.Webhooks,
+		storages: opts.Storages,
+		apps:     opts.Apps,
+		config:   opts.Config,
+		logger:   opts.Logger.Named("WebhookWorkerPool"),
+	}
+}
+
+// Start starts workers. Workers stop picking new jobs when ctx is cancelled or Shutdown is called.
+func (p *WebhookWorkerPool) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	workers := max(p.config.Webhooks.Workers, 1)
+	for i := range workers {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.work(ctx, i)
+		}()
+	}
+
+	p.logger.Info("started webhook workers", "workers", workers)
+}
+
+// Shutdown stops picking new jobs and waits until jobs in progress are finished.
+func (p *WebhookWorkerPool) Shutdown() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+
+	p.logger.Info("stopped webhook workers")
+}
+
+// work processes jobs one by one until ctx is cancelled.
+func (p *WebhookWorkerPool) work(ctx context.Context, worker int) {
+	logger := p.logger.Named("work").With("worker", worker)
+
+	for {
+		// Jobs in progress are finished even if pool is shutting down
+		processed, err := p.processNext(context.WithoutCancel(ctx))
+		if err != nil {
+			logger.Error("failed to process webhook job", "err", err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if processed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.config.Webhooks.PollInterval):
+		}
+	}
+}
+
+// processNext claims and processes the next due job.
+// It returns false if there was no job to process.
+func (p *WebhookWorkerPool) processNext(ctx context.Context) (bool, error) {
+	job, err := p.storages.WebhookJob.Claim(ctx, time.Now().Add(-2*p.config.Webhooks.JobTimeout))
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	logger := p.logger.
+		Named("processNext").
+		With("jobID", job.ID, "webhookID", job.WebhookID, "topic", job.Topic, "app", job.App, "storeName", job.StoreName, "attempt", job.Attempts)
+
+	jobCtx, cancel := context.WithTimeout(ctx, p.config.Webhooks.JobTimeout)
+	defer cancel()
+
+	// Handlers call API with credentials of the app the webhook was delivered to,
+	// jobs of an app which is not configured anymore can't be handled
+	app := p.apps.ByHandle(job.App)
+	if app == nil {
+		err = ErrUnknownApp
+	} else {
+		err = p.router.Dispatch(ContextWithApp(jobCtx, app), &Webhook{
+			ID:         job.WebhookID,
+			Topic:      job.Topic,
+			App:        job.App,
+			StoreName:  job.StoreName,
+			APIVersion: job.APIVersion,
+			Payload:    []byte(job.Payload),
+		})
+	}
+	if err == nil {
+		logger.Info("handled webhook")
+		return true, p.storages.WebhookJob.Delete(ctx, job.ID)
+	}
+
+	errMessage := err.Error()
+	job.LastError = &errMessage
+
+	// Expected errors won't go away on retry
+	if errs.IsExpected(err) || job.Attempts >= p.config.Webhooks.MaxAttempts {
+		logger.Error("webhook job failed permanently, moving to dead letters", "err", err)
+		return true, p.storages.WebhookJob.DeadLetter(ctx, job)
+	}
+
+	job.RunAt = time.Now().Add(p.backoff(job.Attempts))
+	logger.Info("webhook job failed, scheduled retry", "err", err, "runAt", job.RunAt)
+	return true, p.storages.WebhookJob.Reschedule(ctx, job)
+}
+
+// backoff returns delay before the next attempt, doubling it after every failed attempt.
+func (p *WebhookWorkerPool) backoff(attempts int) time.Duration {
+	delay := p.config.Webhooks.RetryBackoff
+	for i := 1; i < attempts && delay < p.config.Webhooks.RetryMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, p.config.Webhooks.RetryMaxBackoff)
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/internal/service"
+	"github.com/antflydb/shopify-app-template-go/pkg/database"
+	"github.com/google/uuid"
+	"github.com/huandu/go-sqlbuilder"
+)
+
+type complianceRequestStorage struct {
+	database.Database
+}
+
+var _ service.ComplianceRequestStorage = (*complianceRequestStorage)(nil)
+
+func NewComplianceRequestStorage(db database.Database) *complianceRequestStorage {
+	return &complianceRequestStorage{db}
+}
+
+func (s *complianceRequestStorage) Create(ctx context.Context, request *entity.ComplianceRequest) (*entity.ComplianceRequest, error) {
+	request.ID = uuid.NewString()
+
+	sb := sqlbuilder.NewInsertBuilder()
+	query, args := sb.
+		InsertInto("compliance_requests").
+		Cols("id", "webhook_id", "topic", "app", "store_name", "customer_id", "payload", "status", "created_at").
+		Values(request.ID, request.WebhookID, request.Topic, request.App, request.StoreName, request.CustomerID, request.Payload, request.Status, request.CreatedAt).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compliance request: %w", err)
+	}
+
+	return request, nil
+}
+
+func (s *complianceRequestStorage) Update(ctx context.Context, request *entity.ComplianceRequest) (*entity.ComplianceRequest, error) {
+	sb := sqlbuilder.NewUpdateBuilder()
+	query, args := sb.
+		Update("compliance_requests").
+		Set(
+			sb.Assign("status", request.Status),
+			sb.Assign("error", request.Error),
+			sb.Assign("completed_at", request.CompletedAt),
+		).
+		Where(sb.Equal("id", request.ID)).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update compliance request: %w", err)
+	}
+
+	return request, nil
+}
+
+func (s *complianceRequestStorage) RedactCustomer(ctx context.Context, app, storeName string, customerID int64) error {
+	sb := sqlbuilder.NewUpdateBuilder()
+	query, args := sb.
+		Update("compliance_requests").
+		Set(sb.Assign("payload", nil)).
+		Where(sb.Equal("app", app)).
+		Where(sb.Equal("store_name", storeName)).
+		Where(sb.Equal("customer_id", customerID)).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to redact customer's compliance requests: %w", err)
+	}
+
+	return nil
+}
+
+func (s *complianceRequestStorage) RedactStore(ctx context.Context, app, storeName string) error {
+	sb := sqlbuilder.NewUpdateBuilder()
+	query, args := sb.
+		Update("compliance_requests").
+		Set(sb.Assign("payload", nil)).
+		Where(sb.Equal("app", app)).
+		Where(sb.Equal("store_name", storeName)).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to redact store's compliance requests: %w", err)
+	}
+
+	return nil
+}
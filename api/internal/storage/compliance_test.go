@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+func TestComplianceRequestRedaction(t *testing.T) {
+	const shop = "example.myshopify.com"
+	customerID := int64(1)
+	otherCustomerID := int64(2)
+
+	tests := []struct {
+		name   string
+		redact func(ctx context.Context, s *complianceRequestStorage) error
+		// wantRedacted are IDs of requests whose payloads are erased
+		wantRedacted []string
+	}{
+		{
+			name: "customer",
+			redact: func(ctx context.Context, s *complianceRequestStorage) error {
+				return s.RedactCustomer(ctx, "staging", shop, customerID)
+			},
+			wantRedacted: []string{"staging-customer"},
+		},
+		{
+			name: "store",
+			redact: func(ctx context.Context, s *complianceRequestStorage) error {
+				return s.RedactStore(ctx, "staging", shop)
+			},
+			wantRedacted: []string{"staging-customer", "staging-other-customer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newTestDatabase(t)
+			s := NewComplianceRequestStorage(db)
+
+			// Both apps are installed on the same shop and received requests about the same customers
+			ids := make(map[string]string)
+			for _, app := range []string{entity.DefaultAppHandle, "staging"} {
+				for name, id := range map[string]*int64{"customer": &customerID, "other-customer": &otherCustomerID} {
+					payload := `{"customer":{"id":1}}`
+					request, err := s.Create(ctx, &entity.ComplianceRequest{
+						Topic:      "customers/data_request",
+						App:        app,
+						StoreName:  shop,
+						CustomerID: id,
+						Payload:    &payload,
+						Status:     entity.ComplianceRequestStatusReceived,
+						CreatedAt:  time.Now(),
+					})
+					if err != nil {
+						t.Fatalf("failed to create compliance request: %v", err)
+					}
+					ids[app+"-"+name] = request.ID
+				}
+			}
+
+			err := tt.redact(ctx, s)
+			if err != nil {
+				t.Fatalf("failed to redact: %v", err)
+			}
+
+			for key, id := range ids {
+				var payload *string
+				err = db.QueryRow(ctx, "SELECT payload FROM compliance_requests WHERE id = ?", id).Scan(&payload)
+				if err != nil {
+					t.Fatalf("failed to get compliance request: %v", err)
+				}
+				wantRedacted := slices.Contains(tt.wantRedacted, key)
+				if (payload == nil) != wantRedacted {
+					t.Errorf("%s: payload redacted = %t, want %t", key, payload == nil, wantRedacted)
+				}
+			}
+		})
+	}
+}
@@ -10,32 +10,86 @@ import (
 	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/internal/service"
 	"github.com/antflydb/shopify-app-template-go/pkg/database"
+	"github.com/antflydb/shopify-app-template-go/pkg/encryption"
 	"github.com/huandu/go-sqlbuilder"
 )
 
 type sessionStorage struct {
 	database.Database
+	keyring *encryption.Keyring
 }
 
 var _ service.SessionStorage = (*sessionStorage)(nil)
 
-func NewSessionStorage(db database.Database) *sessionStorage {
-	return &sessionStorage{db}
+// NewSessionStorage creates session storage, access tokens are encrypted with the keyring at rest.
+func NewSessionStorage(db database.Database, keyring *encryption.Keyring) *sessionStorage {
+	return &sessionStorage{
+		Database: db,
+		keyring:  keyring,
+	}
+}
+
+// sessionColumns are selected by all queries returning sessions, in the order expected by scanSession.
+var sessionColumns = []string{
+	"session_id", "store_id", "shop", "is_online", "user_id", "access_token", "scope", "expires_at",
+	"user_email", "user_account_owner", "user_collaborator", "user_scope",
+}
+
+// scanSession scans session selected with sessionColumns and decrypts its access token.
+func (s *sessionStorage) scanSession(row database.Row) (*entity.Session, error) {
+	var session entity.Session
+	var user entity.AssociatedUser
+	err := row.Scan(
+		&session.SessionID,
+		&session.StoreID,
+		&session.Shop,
+		&session.IsOnline,
+		&session.UserID,
+		&session.AccessToken,
+		&session.Scope,
+		&session.ExpiresAt,
+		&user.Email,
+		&user.AccountOwner,
+		&user.Collaborator,
+		&user.Scope,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsOnline {
+		user.ID = session.UserID
+		session.AssociatedUser = &user
+	}
+
+	session.AccessToken, err = s.keyring.Decrypt(session.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt access token of session %s: %w", session.SessionID, err)
+	}
+
+	return &session, nil
+}
+
+// sessionValues returns values of session's sessionColumns with the encrypted access token.
+func sessionValues(session *entity.Session, accessToken string) []any {
+	user := session.AssociatedUser
+	if user == nil {
+		user = &entity.AssociatedUser{}
+	}
+	return []any{
+		session.SessionID, session.StoreID, session.Shop, session.IsOnline, session.UserID, accessToken, session.Scope, session.ExpiresAt,
+		user.Email, user.AccountOwner, user.Collaborator, user.Scope,
+	}
 }
 
 func (s *sessionStorage) Get(ctx context.Context, sessionID string) (*entity.Session, error) {
 	sb := sqlbuilder.NewSelectBuilder()
 	query, args := sb.
-		Select("session_id", "store_id").
+		Select(sessionColumns...).
 		From("sessions").
 		Where(sb.Equal("session_id", sessionID)).
 		Build()
 
-	var session entity.Session
-	err := s.QueryRow(ctx, query, args...).Scan(
-		&session.SessionID,
-		&session.StoreID,
-	)
+	session, err := s.scanSession(s.QueryRow(ctx, query, args...))
 	if errors.Is(err, sql.ErrNoRows) || (err != nil && strings.Contains(err.Error(), "no rows in result set")) {
 		return nil, nil
 	}
@@ -43,18 +97,23 @@ func (s *sessionStorage) Get(ctx context.Context, sessionID string) (*entity.Ses
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
-	return &session, nil
+	return session, nil
 }
 
 func (s *sessionStorage) Create(ctx context.Context, session *entity.Session) (*entity.Session, error) {
+	accessToken, err := s.keyring.Encrypt(session.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+
 	sb := sqlbuilder.NewInsertBuilder()
 	query, args := sb.
 		InsertInto("sessions").
-		Cols("session_id", "store_id").
-		Values(session.SessionID, session.StoreID).
+		Cols(sessionColumns...).
+		Values(sessionValues(session, accessToken)...).
 		Build()
 
-	_, err := s.Exec(ctx, query, args...)
+	_, err = s.Exec(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -62,6 +121,107 @@ func (s *sessionStorage) Create(ctx context.Context, session *entity.Session) (*
 	return session, nil
 }
 
+func (s *sessionStorage) ListByStore(ctx context.Context, storeID string) ([]*entity.Session, error) {
+	sb := sqlbuilder.NewSelectBuilder()
+	query, args := sb.
+		Select(sessionColumns...).
+		From("sessions").
+		Where(sb.Equal("store_id", storeID)).
+		OrderBy("session_id").
+		Build()
+
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*entity.Session
+	for rows.Next() {
+		session, err := s.scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (s *sessionStorage) Update(ctx context.Context, session *entity.Session) (*entity.Session, error) {
+	accessToken, err := s.keyring.Encrypt(session.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+
+	user := session.AssociatedUser
+	if user == nil {
+		user = &entity.AssociatedUser{}
+	}
+
+	sb := sqlbuilder.NewUpdateBuilder()
+	query, args := sb.
+		Update("sessions").
+		Set(
+			sb.Assign("store_id", session.StoreID),
+			sb.Assign("shop", session.Shop),
+			sb.Assign("is_online", session.IsOnline),
+			sb.Assign("user_id", session.UserID),
+			sb.Assign("access_token", accessToken),
+			sb.Assign("scope", session.Scope),
+			sb.Assign("expires_at", session.ExpiresAt),
+			sb.Assign("user_email", user.Email),
+			sb.Assign("user_account_owner", user.AccountOwner),
+			sb.Assign("user_collaborator", user.Collaborator),
+			sb.Assign("user_scope", user.Scope),
+		).
+		Where(sb.Equal("session_id", session.SessionID)).
+		Build()
+
+	result, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+	if affected == 0 {
+		return nil, fmt.Errorf("session %s is not found", session.SessionID)
+	}
+
+	return session, nil
+}
+
+func (s *sessionStorage) Save(ctx context.Context, session *entity.Session) (*entity.Session, error) {
+	accessToken, err := s.keyring.Encrypt(session.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+
+	sb := sqlbuilder.NewInsertBuilder()
+	query, args := sb.
+		InsertInto("sessions").
+		Cols(sessionColumns...).
+		Values(sessionValues(session, accessToken)...).
+		SQL("ON CONFLICT (session_id) DO UPDATE SET " +
+			"store_id = excluded.store_id, shop = excluded.shop, is_online = excluded.is_online, user_id = excluded.user_id, " +
+			"access_token = excluded.access_token, scope = excluded.scope, expires_at = excluded.expires_at, " +
+			"user_email = excluded.user_email, user_account_owner = excluded.user_account_owner, " +
+			"user_collaborator = excluded.user_collaborator, user_scope = excluded.user_scope").
+		Build()
+
+	_, err = s.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return session, nil
+}
+
 func (s *sessionStorage) Delete(ctx context.Context, sessionID string) error {
 	sb := sqlbuilder.NewDeleteBuilder()
 	query, args := sb.
@@ -76,3 +236,90 @@ func (s *sessionStorage) Delete(ctx context.Context, sessionID string) error {
 
 	return nil
 }
+
+func (s *sessionStorage) DeleteByStore(ctx context.Context, storeID string) (int64, error) {
+	sb := sqlbuilder.NewDeleteBuilder()
+	query, args := sb.
+		DeleteFrom("sessions").
+		Where(sb.Equal("store_id", storeID)).
+		Build()
+
+	result, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// ReencryptAccessTokens encrypts access tokens of sessions stored in plaintext or with keys other than the active one
+// with the active key. It returns number of re-encrypted tokens.
+// Token changed concurrently is skipped, as it's already written with the active key.
+func (s *sessionStorage) ReencryptAccessTokens(ctx context.Context) (int, error) {
+	sb := sqlbuilder.NewSelectBuilder()
+	query, args := sb.
+		Select("session_id", "access_token").
+		From("sessions").
+		Where(sb.NotEqual("access_token", "")).
+		Build()
+
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list session access tokens: %w", err)
+	}
+
+	// Rows are read before updating, as sqlite doesn't allow writes while rows are open
+	tokens := make(map[string]string)
+	for rows.Next() {
+		var sessionID, token string
+		err = rows.Scan(&sessionID, &token)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan session access token: %w", err)
+		}
+		if s.keyring.NeedsRotation(token) {
+			tokens[sessionID] = token
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list session access tokens: %w", err)
+	}
+
+	reencrypted := 0
+	for sessionID, oldToken := range tokens {
+		plaintext, err := s.keyring.Decrypt(oldToken)
+		if err != nil {
+			return reencrypted, fmt.Errorf("failed to decrypt access token of session %s: %w", sessionID, err)
+		}
+		newToken, err := s.keyring.Encrypt(plaintext)
+		if err != nil {
+			return reencrypted, fmt.Errorf("failed to encrypt access token of session %s: %w", sessionID, err)
+		}
+
+		ub := sqlbuilder.NewUpdateBuilder()
+		query, args := ub.
+			Update("sessions").
+			Set(ub.Assign("access_token", newToken)).
+			Where(ub.Equal("session_id", sessionID)).
+			Where(ub.Equal("access_token", oldToken)).
+			Build()
+
+		result, err := s.Exec(ctx, query, args...)
+		if err != nil {
+			return reencrypted, fmt.Errorf("failed to update access token of session %s: %w", sessionID, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return reencrypted, fmt.Errorf("failed to update access token of session %s: %w", sessionID, err)
+		}
+		reencrypted += int(affected)
+	}
+
+	return reencrypted, nil
+}
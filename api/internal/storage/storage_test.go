@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/antflydb/shopify-app-template-go/pkg/database"
+	"github.com/antflydb/shopify-app-template-go/pkg/encryption"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// newTestDatabase returns sqlite database in a temporary file with all migrations applied.
+func newTestDatabase(t *testing.T) database.Database {
+	t.Helper()
+
+	path := t.TempDir() + "/test.db"
+	m, err := migrate.New("file://../../migrations/sqlite", "sqlite3://"+path)
+	if err != nil {
+		t.Fatalf("failed to create migrate instance: %v", err)
+	}
+	err = m.Up()
+	if err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	m.Close()
+
+	db, err := database.NewSQLite(context.Background(), &database.SQLiteConfig{Path: path})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestKeyring returns keyring of keys with the IDs, the first one is active.
+// Every key is filled with the first byte of its ID, so keyrings with the same IDs share keys.
+func newTestKeyring(t *testing.T, keyIDs ...string) *encryption.Keyring {
+	t.Helper()
+
+	pairs := make([]string, 0, len(keyIDs))
+	for _, keyID := range keyIDs {
+		key := []byte(strings.Repeat(keyID[:1], 32))
+		pairs = append(pairs, keyID+":"+base64.StdEncoding.EncodeToString(key))
+	}
+	keyring, err := encryption.NewKeyring(strings.Join(pairs, ","))
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	return keyring
+}
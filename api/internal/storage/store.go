@@ -11,44 +11,80 @@ import (
 	"github.com/antflydb/shopify-app-template-go/internal/entity"
 	"github.com/antflydb/shopify-app-template-go/internal/service"
 	"github.com/antflydb/shopify-app-template-go/pkg/database"
+	"github.com/antflydb/shopify-app-template-go/pkg/encryption"
 	"github.com/antflydb/shopify-app-template-go/pkg/logging"
 	"github.com/huandu/go-sqlbuilder"
 )
 
 type storeStorage struct {
 	database.Database
-	logger logging.Logger
+	keyring *encryption.Keyring
+	logger  logging.Logger
 }
 
 var _ service.StoreStorage = (*storeStorage)(nil)
 
-func NewStoreStorage(db database.Database) *storeStorage {
+// NewStoreStorage creates store storage, access tokens are encrypted with the keyring at rest.
+func NewStoreStorage(db database.Database, keyring *encryption.Keyring) *storeStorage {
 	return &storeStorage{
 		Database: db,
+		keyring:  keyring,
 		logger:   logging.NewZap("info").Named("StoreStorage"),
 	}
 }
 
-func (s *storeStorage) Get(ctx context.Context, storeName string) (*entity.Store, error) {
-	sb := sqlbuilder.NewSelectBuilder()
-	query, args := sb.
-		Select("id", "name", "nonce", "access_token", "installed", "created_at", "updated_at", "deleted_at").
-		From("stores").
-		Where(sb.Equal("name", storeName)).
-		Where(sb.IsNull("deleted_at")).
-		Build()
+// storeColumns are selected by all queries returning stores, in the order expected by scanStore.
+var storeColumns = []string{
+	"id", "app", "name", "nonce", "nonce_created_at", "access_token", "scopes", "installed",
+	"access_token_expires_at", "refresh_token", "refresh_token_expires_at", "created_at", "updated_at", "deleted_at",
+}
 
+// scanStore scans store selected with storeColumns and decrypts its access token.
+func (s *storeStorage) scanStore(row database.Row) (*entity.Store, error) {
 	var store entity.Store
-	err := s.QueryRow(ctx, query, args...).Scan(
+	err := row.Scan(
 		&store.ID,
+		&store.App,
 		&store.Name,
 		&store.Nonce,
+		&store.NonceCreatedAt,
 		&store.AccessToken,
+		&store.Scopes,
 		&store.Installed,
+		&store.AccessTokenExpiresAt,
+		&store.RefreshToken,
+		&store.RefreshTokenExpiresAt,
 		&store.CreatedAt,
 		&store.UpdatedAt,
 		&store.DeletedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	store.AccessToken, err = s.keyring.Decrypt(store.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt access token of store %s: %w", store.Name, err)
+	}
+	store.RefreshToken, err = s.keyring.Decrypt(store.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt refresh token of store %s: %w", store.Name, err)
+	}
+
+	return &store, nil
+}
+
+func (s *storeStorage) Get(ctx context.Context, app, storeName string) (*entity.Store, error) {
+	sb := sqlbuilder.NewSelectBuilder()
+	query, args := sb.
+		Select(storeColumns...).
+		From("stores").
+		Where(sb.Equal("app", app)).
+		Where(sb.Equal("name", storeName)).
+		Where(sb.IsNull("deleted_at")).
+		Build()
+
+	store, err := s.scanStore(s.QueryRow(ctx, query, args...))
 	if errors.Is(err, sql.ErrNoRows) || (err != nil && strings.Contains(err.Error(), "no rows in result set")) {
 		return nil, nil
 	}
@@ -56,13 +92,55 @@ func (s *storeStorage) Get(ctx context.Context, storeName string) (*entity.Store
 		return nil, fmt.Errorf("failed to get store: %w", err)
 	}
 
-	return &store, nil
+	return store, nil
+}
+
+func (s *storeStorage) ListInstalled(ctx context.Context) ([]*entity.Store, error) {
+	sb := sqlbuilder.NewSelectBuilder()
+	query, args := sb.
+		Select(storeColumns...).
+		From("stores").
+		Where(sb.Equal("installed", true)).
+		Where(sb.IsNull("deleted_at")).
+		OrderBy("name").
+		Build()
+
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed stores: %w", err)
+	}
+	defer rows.Close()
+
+	var stores []*entity.Store
+	for rows.Next() {
+		store, err := s.scanStore(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan store: %w", err)
+		}
+		stores = append(stores, store)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list installed stores: %w", err)
+	}
+
+	return stores, nil
 }
 
 func (s *storeStorage) Update(ctx context.Context, store *entity.Store) (*entity.Store, error) {
-	logger := s.logger.Named("Update").WithContext(ctx).With("storeName", store.Name)
+	logger := s.logger.Named("Update").WithContext(ctx).With("app", store.App, "storeName", store.Name)
 	logger.Info("attempting to update store in database", "installed", store.Installed, "hasAccessToken", store.AccessToken != "")
 
+	accessToken, err := s.keyring.Encrypt(store.AccessToken)
+	if err != nil {
+		logger.Error("failed to encrypt access token", "err", err)
+		return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	refreshToken, err := s.keyring.Encrypt(store.RefreshToken)
+	if err != nil {
+		logger.Error("failed to encrypt refresh token", "err", err)
+		return nil, fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+
 	now := time.Now()
 	store.UpdatedAt = now
 
@@ -71,22 +149,28 @@ func (s *storeStorage) Update(ctx context.Context, store *entity.Store) (*entity
 		Update("stores").
 		Set(
 			sb.Assign("nonce", store.Nonce),
-			sb.Assign("access_token", store.AccessToken),
+			sb.Assign("nonce_created_at", store.NonceCreatedAt),
+			sb.Assign("access_token", accessToken),
+			sb.Assign("scopes", store.Scopes),
 			sb.Assign("installed", store.Installed),
+			sb.Assign("access_token_expires_at", store.AccessTokenExpiresAt),
+			sb.Assign("refresh_token", refreshToken),
+			sb.Assign("refresh_token_expires_at", store.RefreshTokenExpiresAt),
 			sb.Assign("updated_at", now),
 		).
+		Where(sb.Equal("app", store.App)).
 		Where(sb.Equal("name", store.Name)).
 		Where(sb.IsNull("deleted_at")).
 		Build()
 
 	logger.Debug("executing update query", "query", query)
-	_, err := s.Exec(ctx, query, args...)
+	_, err = s.Exec(ctx, query, args...)
 	if err != nil {
 		logger.Error("failed to execute update query", "err", err)
 		return nil, fmt.Errorf("failed to update store: %w", err)
 	}
 
-	updatedStore, err := s.Get(ctx, store.Name)
+	updatedStore, err := s.Get(ctx, store.App, store.Name)
 	if err != nil {
 		logger.Error("failed to get updated store after update", "err", err)
 		return nil, fmt.Errorf("failed to get updated store: %w", err)
@@ -97,9 +181,20 @@ func (s *storeStorage) Update(ctx context.Context, store *entity.Store) (*entity
 }
 
 func (s *storeStorage) Create(ctx context.Context, store *entity.Store) (*entity.Store, error) {
-	logger := s.logger.Named("Create").WithContext(ctx).With("storeName", store.Name)
+	logger := s.logger.Named("Create").WithContext(ctx).With("app", store.App, "storeName", store.Name)
 	logger.Info("attempting to create store in database", "storeId", store.ID, "installed", store.Installed)
 
+	accessToken, err := s.keyring.Encrypt(store.AccessToken)
+	if err != nil {
+		logger.Error("failed to encrypt access token", "err", err)
+		return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	refreshToken, err := s.keyring.Encrypt(store.RefreshToken)
+	if err != nil {
+		logger.Error("failed to encrypt refresh token", "err", err)
+		return nil, fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+
 	now := time.Now()
 	store.CreatedAt = now
 	store.UpdatedAt = now
@@ -107,28 +202,180 @@ func (s *storeStorage) Create(ctx context.Context, store *entity.Store) (*entity
 	sb := sqlbuilder.NewInsertBuilder()
 	query, args := sb.
 		InsertInto("stores").
-		Cols("name", "nonce", "access_token", "installed", "created_at", "updated_at").
-		Values(store.Name, store.Nonce, store.AccessToken, store.Installed, store.CreatedAt, store.UpdatedAt).
+		Cols(
+			"app", "name", "nonce", "nonce_created_at", "access_token", "scopes", "installed",
+			"access_token_expires_at", "refresh_token", "refresh_token_expires_at", "created_at", "updated_at",
+		).
+		Values(
+			store.App, store.Name, store.Nonce, store.NonceCreatedAt, accessToken, store.Scopes, store.Installed,
+			store.AccessTokenExpiresAt, refreshToken, store.RefreshTokenExpiresAt, store.CreatedAt, store.UpdatedAt,
+		).
 		Build()
 
-	logger.Debug("executing create query", "query", query, "args", args)
-	_, err := s.Exec(ctx, query, args...)
+	logger.Debug("executing create query", "query", query)
+	_, err = s.Exec(ctx, query, args...)
 	if err != nil {
 		logger.Error("failed to execute create query", "err", err)
 		return nil, fmt.Errorf("failed to create store: %w", err)
 	}
 
-	logger.Info("successfully created store in database", "storeId", store.ID, "storeName", store.Name)
-	return store, nil
+	// Store ID is generated by database
+	createdStore, err := s.Get(ctx, store.App, store.Name)
+	if err != nil {
+		logger.Error("failed to get created store", "err", err)
+		return nil, fmt.Errorf("failed to get created store: %w", err)
+	}
+
+	logger.Info("successfully created store in database", "storeId", createdStore.ID, "storeName", createdStore.Name)
+	return createdStore, nil
 }
 
-func (s *storeStorage) Delete(ctx context.Context, storeName string) error {
+// ReencryptAccessTokens encrypts access and refresh tokens stored in plaintext or with keys other than the active one
+// with the active key. It returns number of re-encrypted tokens.
+// Token changed concurrently is skipped, as it's already written with the active key.
+func (s *storeStorage) ReencryptAccessTokens(ctx context.Context) (int, error) {
+	logger := s.logger.Named("ReencryptAccessTokens").WithContext(ctx)
+
+	reencrypted := 0
+	for _, column := range []string{"access_token", "refresh_token"} {
+		n, err := s.reencryptColumn(ctx, column)
+		reencrypted += n
+		if err != nil {
+			return reencrypted, err
+		}
+	}
+
+	logger.Info("re-encrypted access tokens", "reencrypted", reencrypted, "keyID", s.keyring.ActiveKeyID())
+	return reencrypted, nil
+}
+
+// reencryptColumn re-encrypts tokens stored in the column with the active key.
+func (s *storeStorage) reencryptColumn(ctx context.Context, column string) (int, error) {
+	sb := sqlbuilder.NewSelectBuilder()
+	query, args := sb.
+		Select("id", column).
+		From("stores").
+		Where(sb.NotEqual(column, "")).
+		Build()
+
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", column, err)
+	}
+
+	// Rows are read before updating, as sqlite doesn't allow writes while rows are open
+	tokens := make(map[string]string)
+	for rows.Next() {
+		var id, token string
+		err = rows.Scan(&id, &token)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan %s: %w", column, err)
+		}
+		if s.keyring.NeedsRotation(token) {
+			tokens[id] = token
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", column, err)
+	}
+
+	reencrypted := 0
+	for id, oldToken := range tokens {
+		plaintext, err := s.keyring.Decrypt(oldToken)
+		if err != nil {
+			return reencrypted, fmt.Errorf("failed to decrypt %s of store %s: %w", column, id, err)
+		}
+		newToken, err := s.keyring.Encrypt(plaintext)
+		if err != nil {
+			return reencrypted, fmt.Errorf("failed to encrypt %s of store %s: %w", column, id, err)
+		}
+
+		ub := sqlbuilder.NewUpdateBuilder()
+		query, args := ub.
+			Update("stores").
+			Set(ub.Assign(column, newToken)).
+			Where(ub.Equal("id", id)).
+			Where(ub.Equal(column, oldToken)).
+			Build()
+
+		result, err := s.Exec(ctx, query, args...)
+		if err != nil {
+			return reencrypted, fmt.Errorf("failed to update %s of store %s: %w", column, id, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return reencrypted, fmt.Errorf("failed to update %s of store %s: %w", column, id, err)
+		}
+		reencrypted += int(affected)
+	}
+
+	return reencrypted, nil
+}
+
+func (s *storeStorage) ConsumeNonce(ctx context.Context, app, storeName, nonce string) (bool, error) {
+	// Store without authorization in progress has empty nonce
+	if nonce == "" {
+		return false, nil
+	}
+
+	sb := sqlbuilder.NewUpdateBuilder()
+	query, args := sb.
+		Update("stores").
+		Set(
+			sb.Assign("nonce", ""),
+			sb.Assign("nonce_created_at", nil),
+			sb.Assign("updated_at", time.Now()),
+		).
+		Where(sb.Equal("app", app)).
+		Where(sb.Equal("name", storeName)).
+		Where(sb.Equal("nonce", nonce)).
+		Where(sb.IsNull("deleted_at")).
+		Build()
+
+	result, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume nonce: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume nonce: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (s *storeStorage) UpdateScopes(ctx context.Context, app, storeName, scopes string) error {
+	sb := sqlbuilder.NewUpdateBuilder()
+	query, args := sb.
+		Update("stores").
+		Set(
+			sb.Assign("scopes", scopes),
+			sb.Assign("updated_at", time.Now()),
+		).
+		Where(sb.Equal("app", app)).
+		Where(sb.Equal("name", storeName)).
+		Where(sb.IsNull("deleted_at")).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update scopes: %w", err)
+	}
+
+	return nil
+}
+
+func (s *storeStorage) Delete(ctx context.Context, app, storeName string) error {
 	now := time.Now()
 
 	sb := sqlbuilder.NewUpdateBuilder()
 	query, args := sb.
 		Update("stores").
 		Set(sb.Assign("deleted_at", now)).
+		Where(sb.Equal("app", app)).
 		Where(sb.Equal("name", storeName)).
 		Where(sb.IsNull("deleted_at")).
 		Build()
@@ -140,3 +387,48 @@ func (s *storeStorage) Delete(ctx context.Context, storeName string) error {
 
 	return nil
 }
+
+func (s *storeStorage) Erase(ctx context.Context, app, storeName string) error {
+	// Sessions reference stores by id, so they have to be erased first
+	ssb := sqlbuilder.NewSelectBuilder()
+	ssb.Select("id").From("stores").Where(ssb.Equal("app", app), ssb.Equal("name", storeName))
+
+	db := sqlbuilder.NewDeleteBuilder()
+	query, args := db.
+		DeleteFrom("sessions").
+		Where(db.In("store_id", ssb)).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to erase store's sessions: %w", err)
+	}
+
+	for _, table := range []string{"processed_webhooks", "webhook_jobs", "webhook_dead_letters"} {
+		jb := sqlbuilder.NewDeleteBuilder()
+		query, args = jb.
+			DeleteFrom(table).
+			Where(jb.Equal("app", app)).
+			Where(jb.Equal("store_name", storeName)).
+			Build()
+
+		_, err = s.Exec(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to erase store's %s: %w", table, err)
+		}
+	}
+
+	sb := sqlbuilder.NewDeleteBuilder()
+	query, args = sb.
+		DeleteFrom("stores").
+		Where(sb.Equal("app", app)).
+		Where(sb.Equal("name", storeName)).
+		Build()
+
+	_, err = s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to erase store: %w", err)
+	}
+
+	return nil
+}
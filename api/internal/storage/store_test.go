@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+)
+
+func TestReencryptAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	oldKeyring := newTestKeyring(t, "old")
+
+	// Stores and sessions written with the old key
+	encryptedStore, err := NewStoreStorage(db, oldKeyring).Create(ctx, &entity.Store{
+		App:          entity.DefaultAppHandle,
+		Name:         "encrypted.myshopify.com",
+		AccessToken:  "shpat_encrypted",
+		RefreshToken: "shprt_encrypted",
+	})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	_, err = NewSessionStorage(db, oldKeyring).Save(ctx, &entity.Session{
+		SessionID:   "offline_encrypted.myshopify.com",
+		StoreID:     encryptedStore.ID,
+		Shop:        encryptedStore.Name,
+		AccessToken: "shpat_encrypted_session",
+	})
+	if err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+
+	// Store and session written in plaintext before encryption was enabled
+	_, err = db.Exec(ctx, "INSERT INTO stores (id, name, nonce, access_token, installed) VALUES ('plain', 'plain.myshopify.com', '', 'shpat_plain', 1)")
+	if err != nil {
+		t.Fatalf("failed to insert plaintext store: %v", err)
+	}
+	_, err = db.Exec(ctx, "INSERT INTO sessions (session_id, store_id, shop, access_token) VALUES ('offline_plain.myshopify.com', 'plain', 'plain.myshopify.com', 'shpat_plain_session')")
+	if err != nil {
+		t.Fatalf("failed to insert plaintext session: %v", err)
+	}
+
+	rotatingKeyring := newTestKeyring(t, "new", "old")
+	reencrypted, err := NewStoreStorage(db, rotatingKeyring).ReencryptAccessTokens(ctx)
+	if err != nil {
+		t.Fatalf("failed to re-encrypt store tokens: %v", err)
+	}
+	// Access and refresh token of the encrypted store, and access token of the plaintext one
+	if reencrypted != 3 {
+		t.Errorf("re-encrypted store tokens = %d, want 3", reencrypted)
+	}
+	reencrypted, err = NewSessionStorage(db, rotatingKeyring).ReencryptAccessTokens(ctx)
+	if err != nil {
+		t.Fatalf("failed to re-encrypt session tokens: %v", err)
+	}
+	if reencrypted != 2 {
+		t.Errorf("re-encrypted session tokens = %d, want 2", reencrypted)
+	}
+
+	// Nothing is left to re-encrypt
+	reencrypted, err = NewStoreStorage(db, rotatingKeyring).ReencryptAccessTokens(ctx)
+	if err != nil || reencrypted != 0 {
+		t.Errorf("re-encrypting stores again = %d, %v, want 0", reencrypted, err)
+	}
+	reencrypted, err = NewSessionStorage(db, rotatingKeyring).ReencryptAccessTokens(ctx)
+	if err != nil || reencrypted != 0 {
+		t.Errorf("re-encrypting sessions again = %d, %v, want 0", reencrypted, err)
+	}
+
+	for _, query := range []string{
+		"SELECT access_token FROM stores",
+		"SELECT refresh_token FROM stores WHERE refresh_token != ''",
+		"SELECT access_token FROM sessions",
+	} {
+		rows, err := db.Query(ctx, query)
+		if err != nil {
+			t.Fatalf("failed to query tokens: %v", err)
+		}
+		for rows.Next() {
+			var token string
+			err = rows.Scan(&token)
+			if err != nil {
+				t.Fatalf("failed to scan token: %v", err)
+			}
+			if !strings.HasPrefix(token, "enc:v1:new:") {
+				t.Errorf("%s: token is not encrypted with the new key: %q", query, token)
+			}
+		}
+		rows.Close()
+	}
+
+	// Old key can be removed
+	newKeyring := newTestKeyring(t, "new")
+	stores := NewStoreStorage(db, newKeyring)
+	sessions := NewSessionStorage(db, newKeyring)
+
+	store, err := stores.Get(ctx, entity.DefaultAppHandle, "encrypted.myshopify.com")
+	if err != nil || store.AccessToken != "shpat_encrypted" || store.RefreshToken != "shprt_encrypted" {
+		t.Errorf("encrypted store = %+v, %v", store, err)
+	}
+	store, err = stores.Get(ctx, entity.DefaultAppHandle, "plain.myshopify.com")
+	if err != nil || store.AccessToken != "shpat_plain" {
+		t.Errorf("plaintext store = %+v, %v", store, err)
+	}
+	session, err := sessions.Get(ctx, "offline_encrypted.myshopify.com")
+	if err != nil || session.AccessToken != "shpat_encrypted_session" {
+		t.Errorf("encrypted session = %+v, %v", session, err)
+	}
+	session, err = sessions.Get(ctx, "offline_plain.myshopify.com")
+	if err != nil || session.AccessToken != "shpat_plain_session" {
+		t.Errorf("plaintext session = %+v, %v", session, err)
+	}
+}
+
+func TestEraseStore(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	stores := NewStoreStorage(db, newTestKeyring(t, "key"))
+	sessions := NewSessionStorage(db, newTestKeyring(t, "key"))
+	processedWebhooks := NewProcessedWebhookStorage(db)
+
+	const shop = "example.myshopify.com"
+	for _, app := range []string{entity.DefaultAppHandle, "staging"} {
+		store, err := stores.Create(ctx, &entity.Store{App: app, Name: shop, AccessToken: "shpat_" + app, Installed: true})
+		if err != nil {
+			t.Fatalf("failed to create store of %s: %v", app, err)
+		}
+		_, err = sessions.Save(ctx, &entity.Session{SessionID: "offline_" + app, StoreID: store.ID, Shop: shop, AccessToken: "shpat_" + app})
+		if err != nil {
+			t.Fatalf("failed to save session of %s: %v", app, err)
+		}
+		_, err = processedWebhooks.Claim(ctx, &entity.ProcessedWebhook{WebhookID: "webhook-" + app, Topic: "orders/create", App: app, StoreName: shop, ProcessedAt: time.Now()})
+		if err != nil {
+			t.Fatalf("failed to claim webhook of %s: %v", app, err)
+		}
+	}
+
+	err := stores.Erase(ctx, "staging", shop)
+	if err != nil {
+		t.Fatalf("failed to erase store: %v", err)
+	}
+
+	tests := []struct {
+		app        string
+		wantErased bool
+	}{
+		{app: "staging", wantErased: true},
+		{app: entity.DefaultAppHandle, wantErased: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.app, func(t *testing.T) {
+			store, err := stores.Get(ctx, tt.app, shop)
+			if err != nil {
+				t.Fatalf("failed to get store: %v", err)
+			}
+			if (store == nil) != tt.wantErased {
+				t.Errorf("store = %+v, erased = %t", store, tt.wantErased)
+			}
+
+			session, err := sessions.Get(ctx, "offline_"+tt.app)
+			if err != nil {
+				t.Fatalf("failed to get session: %v", err)
+			}
+			if (session == nil) != tt.wantErased {
+				t.Errorf("session = %+v, erased = %t", session, tt.wantErased)
+			}
+
+			// Webhook can be claimed again only if it's erased
+			claimed, err := processedWebhooks.Claim(ctx, &entity.ProcessedWebhook{WebhookID: "webhook-" + tt.app, Topic: "orders/create", App: tt.app, StoreName: shop, ProcessedAt: time.Now()})
+			if err != nil {
+				t.Fatalf("failed to claim webhook: %v", err)
+			}
+			if claimed != tt.wantErased {
+				t.Errorf("webhook claimed again = %t, want %t", claimed, tt.wantErased)
+			}
+		})
+	}
+}
+
+func TestConsumeNonce(t *testing.T) {
+	ctx := context.Background()
+	stores := NewStoreStorage(newTestDatabase(t), newTestKeyring(t, "key"))
+
+	const shop = "example.myshopify.com"
+	now := time.Now()
+	_, err := stores.Create(ctx, &entity.Store{App: entity.DefaultAppHandle, Name: shop, Nonce: "nonce", NonceCreatedAt: &now})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		app          string
+		nonce        string
+		wantConsumed bool
+	}{
+		{name: "another nonce", app: entity.DefaultAppHandle, nonce: "other", wantConsumed: false},
+		{name: "another app", app: "staging", nonce: "nonce", wantConsumed: false},
+		{name: "nonce", app: entity.DefaultAppHandle, nonce: "nonce", wantConsumed: true},
+		{name: "consumed nonce", app: entity.DefaultAppHandle, nonce: "nonce", wantConsumed: false},
+		{name: "empty nonce of consumed one", app: entity.DefaultAppHandle, nonce: "", wantConsumed: false},
+	}
+	for _, tt := range tests {
+		consumed, err := stores.ConsumeNonce(ctx, tt.app, shop, tt.nonce)
+		if err != nil {
+			t.Fatalf("%s: failed to consume nonce: %v", tt.name, err)
+		}
+		if consumed != tt.wantConsumed {
+			t.Errorf("%s: consumed = %t, want %t", tt.name, consumed, tt.wantConsumed)
+		}
+	}
+}
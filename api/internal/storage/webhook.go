@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antflydb/shopify-app-template-go/internal/entity"
+	"github.com/antflydb/shopify-app-template-go/internal/service"
+	"github.com/antflydb/shopify-app-template-go/pkg/database"
+	"github.com/google/uuid"
+	"github.com/huandu/go-sqlbuilder"
+)
+
+type processedWebhookStorage struct {
+	database.Database
+}
+
+var _ service.ProcessedWebhookStorage = (*processedWebhookStorage)(nil)
+
+func NewProcessedWebhookStorage(db database.Database) *processedWebhookStorage {
+	return &processedWebhookStorage{db}
+}
+
+func (s *processedWebhookStorage) Claim(ctx context.Context, webhook *entity.ProcessedWebhook) (bool, error) {
+	sb := sqlbuilder.NewInsertBuilder()
+	query, args := sb.
+		InsertInto("processed_webhooks").
+		Cols("webhook_id", "topic", "app", "store_name", "processed_at").
+		Values(webhook.WebhookID, webhook.Topic, webhook.App, webhook.StoreName, webhook.ProcessedAt).
+		SQL("ON CONFLICT (webhook_id) DO NOTHING").
+		Build()
+
+	res, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *processedWebhookStorage) Delete(ctx context.Context, webhookID string) error {
+	sb := sqlbuilder.NewDeleteBuilder()
+	query, args := sb.
+		DeleteFrom("processed_webhooks").
+		Where(sb.Equal("webhook_id", webhookID)).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete processed webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (s *processedWebhookStorage) DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error) {
+	sb := sqlbuilder.NewDeleteBuilder()
+	query, args := sb.
+		DeleteFrom("processed_webhooks").
+		Where(sb.LessThan("processed_at", before)).
+		Build()
+
+	res, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired processed webhooks: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+type webhookJobStorage struct {
+	database.Database
+}
+
+var _ service.WebhookJobStorage = (*webhookJobStorage)(nil)
+
+func NewWebhookJobStorage(db database.Database) *webhookJobStorage {
+	return &webhookJobStorage{db}
+}
+
+func (s *webhookJobStorage) Enqueue(ctx context.Context, job *entity.WebhookJob) (*entity.WebhookJob, error) {
+	now := time.Now()
+	job.ID = uuid.NewString()
+	job.Status = entity.WebhookJobStatusPending
+	job.RunAt = now
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	sb := sqlbuilder.NewInsertBuilder()
+	query, args := sb.
+		InsertInto("webhook_jobs").
+		Cols("id", "webhook_id", "topic", "app", "store_name", "api_version", "payload", "status", "attempts", "run_at", "created_at", "updated_at").
+		Values(job.ID, job.WebhookID, job.Topic, job.App, job.StoreName, job.APIVersion, job.Payload, job.Status, job.Attempts, job.RunAt, job.CreatedAt, job.UpdatedAt).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue webhook job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (s *webhookJobStorage) Claim(ctx context.Context, staleBefore time.Time) (*entity.WebhookJob, error) {
+	now := time.Now()
+	lockID := uuid.NewString()
+
+	// Pick the next due job or a job whose worker has died while running it
+	ssb := sqlbuilder.NewSelectBuilder()
+	ssb.Select("id").
+		From("webhook_jobs").
+		Where(ssb.Or(
+			ssb.And(ssb.Equal("status", entity.WebhookJobStatusPending), ssb.LessEqualThan("run_at", now)),
+			ssb.And(ssb.Equal("status", entity.WebhookJobStatusRunning), ssb.LessThan("locked_at", staleBefore)),
+		)).
+		OrderBy("run_at").
+		Limit(1)
+
+	// Conditions are checked again, so only one of concurrent workers wins the job
+	ub := sqlbuilder.NewUpdateBuilder()
+	query, args := ub.
+		Update("webhook_jobs").
+		Set(
+			ub.Assign("status", entity.WebhookJobStatusRunning),
+			ub.Assign("locked_by", lockID),
+			ub.Assign("locked_at", now),
+			ub.Incr("attempts"),
+			ub.Assign("updated_at", now),
+		).
+		Where(ub.In("id", ssb)).
+		Where(ub.Or(
+			ub.Equal("status", entity.WebhookJobStatusPending),
+			ub.LessThan("locked_at", staleBefore),
+		)).
+		Build()
+
+	res, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook job: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, nil
+	}
+
+	sb := sqlbuilder.NewSelectBuilder()
+	query, args = sb.
+		Select("id", "webhook_id", "topic", "app", "store_name", "api_version", "payload", "status", "attempts", "run_at", "locked_by", "locked_at", "last_error", "created_at", "updated_at").
+		From("webhook_jobs").
+		Where(sb.Equal("locked_by", lockID)).
+		Build()
+
+	var job entity.WebhookJob
+	err = s.QueryRow(ctx, query, args...).Scan(
+		&job.ID,
+		&job.WebhookID,
+		&job.Topic,
+		&job.App,
+		&job.StoreName,
+		&job.APIVersion,
+		&job.Payload,
+		&job.Status,
+		&job.Attempts,
+		&job.RunAt,
+		&job.LockedBy,
+		&job.LockedAt,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get claimed webhook job: %w", err)
+	}
+
+	return &job, nil
+}
+
+func (s *webhookJobStorage) Reschedule(ctx context.Context, job *entity.WebhookJob) error {
+	job.Status = entity.WebhookJobStatusPending
+	job.LockedBy = nil
+	job.LockedAt = nil
+	job.UpdatedAt = time.Now()
+
+	sb := sqlbuilder.NewUpdateBuilder()
+	query, args := sb.
+		Update("webhook_jobs").
+		Set(
+			sb.Assign("status", job.Status),
+			sb.Assign("run_at", job.RunAt),
+			sb.Assign("locked_by", nil),
+			sb.Assign("locked_at", nil),
+			sb.Assign("last_error", job.LastError),
+			sb.Assign("updated_at", job.UpdatedAt),
+		).
+		Where(sb.Equal("id", job.ID)).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule webhook job: %w", err)
+	}
+
+	return nil
+}
+
+func (s *webhookJobStorage) Delete(ctx context.Context, jobID string) error {
+	sb := sqlbuilder.NewDeleteBuilder()
+	query, args := sb.
+		DeleteFrom("webhook_jobs").
+		Where(sb.Equal("id", jobID)).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook job: %w", err)
+	}
+
+	return nil
+}
+
+func (s *webhookJobStorage) DeadLetter(ctx context.Context, job *entity.WebhookJob) error {
+	sb := sqlbuilder.NewInsertBuilder()
+	query, args := sb.
+		InsertInto("webhook_dead_letters").
+		Cols("id", "webhook_id", "topic", "app", "store_name", "api_version", "payload", "attempts", "last_error", "created_at", "failed_at").
+		Values(job.ID, job.WebhookID, job.Topic, job.App, job.StoreName, job.APIVersion, job.Payload, job.Attempts, job.LastError, job.CreatedAt, time.Now()).
+		Build()
+
+	_, err := s.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to move webhook job to dead letters: %w", err)
+	}
+
+	return s.Delete(ctx, job.ID)
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS compliance_requests;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- Create compliance requests table
+CREATE TABLE compliance_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    webhook_id VARCHAR(255),
+    topic VARCHAR(255) NOT NULL,
+    store_name VARCHAR(255) NOT NULL,
+    customer_id BIGINT,
+    payload TEXT,
+    status VARCHAR(32) NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create index for customer lookups
+CREATE INDEX idx_compliance_requests_store_name_customer_id ON compliance_requests (store_name, customer_id);
\ No newline at end of file
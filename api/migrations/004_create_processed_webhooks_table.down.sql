@@ -0,0 +1 @@
+DROP TABLE IF EXISTS processed_webhooks;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- Create processed webhooks table
+CREATE TABLE processed_webhooks (
+    webhook_id VARCHAR(255) PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    store_name VARCHAR(255) NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for expiration lookups
+CREATE INDEX idx_processed_webhooks_processed_at ON processed_webhooks (processed_at);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS webhook_dead_letters;
+DROP TABLE IF EXISTS webhook_jobs;
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- Create webhook jobs table
+CREATE TABLE webhook_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    webhook_id VARCHAR(255),
+    topic VARCHAR(255) NOT NULL,
+    store_name VARCHAR(255) NOT NULL,
+    api_version VARCHAR(32),
+    payload TEXT NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_by VARCHAR(255),
+    locked_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for picking jobs to run
+CREATE INDEX idx_webhook_jobs_status_run_at ON webhook_jobs (status, run_at);
+
+-- Create webhook dead letters table
+CREATE TABLE webhook_dead_letters (
+    id UUID PRIMARY KEY,
+    webhook_id VARCHAR(255),
+    topic VARCHAR(255) NOT NULL,
+    store_name VARCHAR(255) NOT NULL,
+    api_version VARCHAR(32),
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for store lookups
+CREATE INDEX idx_webhook_dead_letters_store_name ON webhook_dead_letters (store_name);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE stores DROP COLUMN nonce_created_at;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Add nonce creation time to stores table
+ALTER TABLE stores ADD COLUMN nonce_created_at TIMESTAMP WITH TIME ZONE;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE stores DROP COLUMN scopes;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Add granted access scopes to stores table
+ALTER TABLE stores ADD COLUMN scopes TEXT NOT NULL DEFAULT '';
\ No newline at end of file
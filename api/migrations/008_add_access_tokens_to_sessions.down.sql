@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_sessions_shop;
+
+ALTER TABLE sessions
+    DROP COLUMN shop,
+    DROP COLUMN is_online,
+    DROP COLUMN user_id,
+    DROP COLUMN access_token,
+    DROP COLUMN scope,
+    DROP COLUMN expires_at;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- Add access token details to sessions table
+ALTER TABLE sessions
+    ADD COLUMN shop VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN is_online BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN user_id VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN access_token TEXT NOT NULL DEFAULT '',
+    ADD COLUMN scope TEXT NOT NULL DEFAULT '',
+    ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Create index for shop lookups
+CREATE INDEX idx_sessions_shop ON sessions (shop);
\ No newline at end of file
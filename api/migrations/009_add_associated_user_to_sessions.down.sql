@@ -0,0 +1,5 @@
+ALTER TABLE sessions
+    DROP COLUMN user_email,
+    DROP COLUMN user_account_owner,
+    DROP COLUMN user_collaborator,
+    DROP COLUMN user_scope;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- Add associated user details to sessions table
+ALTER TABLE sessions
+    ADD COLUMN user_email VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN user_account_owner BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN user_collaborator BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN user_scope TEXT NOT NULL DEFAULT '';
\ No newline at end of file
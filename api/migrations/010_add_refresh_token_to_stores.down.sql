@@ -0,0 +1,4 @@
+ALTER TABLE stores
+    DROP COLUMN access_token_expires_at,
+    DROP COLUMN refresh_token,
+    DROP COLUMN refresh_token_expires_at;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- Add expiring offline access token details to stores table
+ALTER TABLE stores
+    ADD COLUMN access_token_expires_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN refresh_token TEXT NOT NULL DEFAULT '',
+    ADD COLUMN refresh_token_expires_at TIMESTAMP WITH TIME ZONE;
\ No newline at end of file
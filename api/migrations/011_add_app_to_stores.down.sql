@@ -0,0 +1,5 @@
+ALTER TABLE webhook_dead_letters DROP COLUMN app;
+ALTER TABLE webhook_jobs DROP COLUMN app;
+DROP INDEX idx_stores_app_name;
+ALTER TABLE stores DROP COLUMN app;
+ALTER TABLE stores ADD CONSTRAINT stores_name_key UNIQUE (name);
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- Key stores and webhook jobs by app, records created before belong to the default app
+ALTER TABLE stores ADD COLUMN app VARCHAR(255) NOT NULL DEFAULT 'default';
+ALTER TABLE stores DROP CONSTRAINT stores_name_key;
+
+-- Only one not deleted store per app and shop, deleted stores can be installed again
+CREATE UNIQUE INDEX idx_stores_app_name ON stores (app, name) WHERE deleted_at IS NULL;
+
+ALTER TABLE webhook_jobs ADD COLUMN app VARCHAR(255) NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_dead_letters ADD COLUMN app VARCHAR(255) NOT NULL DEFAULT 'default';
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Key processed webhooks by app, records created before belong to the default app
+ALTER TABLE processed_webhooks ADD COLUMN app VARCHAR(255) NOT NULL DEFAULT 'default';
\ No newline at end of file
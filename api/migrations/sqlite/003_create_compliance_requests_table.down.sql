@@ -0,0 +1,2 @@
+-- Drop compliance requests table
+DROP TABLE IF EXISTS compliance_requests;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- Create compliance requests table
+CREATE TABLE compliance_requests (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+    webhook_id TEXT,
+    topic TEXT NOT NULL,
+    store_name TEXT NOT NULL,
+    customer_id INTEGER,
+    payload TEXT,
+    status TEXT NOT NULL,
+    error TEXT,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    completed_at DATETIME
+);
+
+-- Create index for customer lookups
+CREATE INDEX idx_compliance_requests_store_name_customer_id ON compliance_requests (store_name, customer_id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Drop processed webhooks table
+DROP TABLE IF EXISTS processed_webhooks;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- Create processed webhooks table
+CREATE TABLE processed_webhooks (
+    webhook_id TEXT PRIMARY KEY,
+    topic TEXT NOT NULL,
+    store_name TEXT NOT NULL,
+    processed_at DATETIME NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Create index for expiration lookups
+CREATE INDEX idx_processed_webhooks_processed_at ON processed_webhooks (processed_at);
\ No newline at end of file
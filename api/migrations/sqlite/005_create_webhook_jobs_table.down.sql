@@ -0,0 +1,3 @@
+-- Drop webhook job tables
+DROP TABLE IF EXISTS webhook_dead_letters;
+DROP TABLE IF EXISTS webhook_jobs;
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- Create webhook jobs table
+CREATE TABLE webhook_jobs (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+    webhook_id TEXT,
+    topic TEXT NOT NULL,
+    store_name TEXT NOT NULL,
+    api_version TEXT,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    locked_by TEXT,
+    locked_at DATETIME,
+    last_error TEXT,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at DATETIME NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Create index for picking jobs to run
+CREATE INDEX idx_webhook_jobs_status_run_at ON webhook_jobs (status, run_at);
+
+-- Create webhook dead letters table
+CREATE TABLE webhook_dead_letters (
+    id TEXT PRIMARY KEY,
+    webhook_id TEXT,
+    topic TEXT NOT NULL,
+    store_name TEXT NOT NULL,
+    api_version TEXT,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT,
+    created_at DATETIME NOT NULL,
+    failed_at DATETIME NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Create index for store lookups
+CREATE INDEX idx_webhook_dead_letters_store_name ON webhook_dead_letters (store_name);
\ No newline at end of file
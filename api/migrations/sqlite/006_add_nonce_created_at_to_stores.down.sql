@@ -0,0 +1,2 @@
+-- Drop nonce creation time from stores table
+ALTER TABLE stores DROP COLUMN nonce_created_at;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Add nonce creation time to stores table
+ALTER TABLE stores ADD COLUMN nonce_created_at DATETIME;
\ No newline at end of file
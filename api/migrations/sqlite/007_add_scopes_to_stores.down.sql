@@ -0,0 +1,2 @@
+-- Drop granted access scopes from stores table
+ALTER TABLE stores DROP COLUMN scopes;
\ No newline at end of file
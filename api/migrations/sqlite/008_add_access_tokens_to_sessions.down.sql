@@ -0,0 +1,8 @@
+-- Drop access token details from sessions table
+DROP INDEX IF EXISTS idx_sessions_shop;
+ALTER TABLE sessions DROP COLUMN shop;
+ALTER TABLE sessions DROP COLUMN is_online;
+ALTER TABLE sessions DROP COLUMN user_id;
+ALTER TABLE sessions DROP COLUMN access_token;
+ALTER TABLE sessions DROP COLUMN scope;
+ALTER TABLE sessions DROP COLUMN expires_at;
\ No newline at end of file
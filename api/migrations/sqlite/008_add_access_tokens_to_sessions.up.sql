@@ -0,0 +1,10 @@
+-- Add access token details to sessions table
+ALTER TABLE sessions ADD COLUMN shop TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN is_online BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN user_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN access_token TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN expires_at DATETIME;
+
+-- Create index for shop lookups
+CREATE INDEX idx_sessions_shop ON sessions (shop);
\ No newline at end of file
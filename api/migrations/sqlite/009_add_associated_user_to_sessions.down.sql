@@ -0,0 +1,5 @@
+-- Drop associated user details from sessions table
+ALTER TABLE sessions DROP COLUMN user_email;
+ALTER TABLE sessions DROP COLUMN user_account_owner;
+ALTER TABLE sessions DROP COLUMN user_collaborator;
+ALTER TABLE sessions DROP COLUMN user_scope;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- Add associated user details to sessions table
+ALTER TABLE sessions ADD COLUMN user_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN user_account_owner BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN user_collaborator BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN user_scope TEXT NOT NULL DEFAULT '';
\ No newline at end of file
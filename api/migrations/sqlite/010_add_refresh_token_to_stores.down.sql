@@ -0,0 +1,4 @@
+-- Drop expiring offline access token details from stores table
+ALTER TABLE stores DROP COLUMN access_token_expires_at;
+ALTER TABLE stores DROP COLUMN refresh_token;
+ALTER TABLE stores DROP COLUMN refresh_token_expires_at;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- Add expiring offline access token details to stores table
+ALTER TABLE stores ADD COLUMN access_token_expires_at DATETIME;
+ALTER TABLE stores ADD COLUMN refresh_token TEXT NOT NULL DEFAULT '';
+ALTER TABLE stores ADD COLUMN refresh_token_expires_at DATETIME;
\ No newline at end of file
@@ -0,0 +1,28 @@
+-- Restore stores keyed by shop only
+ALTER TABLE webhook_dead_letters DROP COLUMN app;
+ALTER TABLE webhook_jobs DROP COLUMN app;
+
+CREATE TABLE stores_old (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+    name TEXT NOT NULL UNIQUE,
+    nonce TEXT,
+    access_token TEXT,
+    installed INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    deleted_at DATETIME,
+    nonce_created_at DATETIME,
+    scopes TEXT NOT NULL DEFAULT '',
+    access_token_expires_at DATETIME,
+    refresh_token TEXT NOT NULL DEFAULT '',
+    refresh_token_expires_at DATETIME
+);
+
+INSERT INTO stores_old (id, name, nonce, access_token, installed, created_at, updated_at, deleted_at, nonce_created_at, scopes, access_token_expires_at, refresh_token, refresh_token_expires_at)
+SELECT id, name, nonce, access_token, installed, created_at, updated_at, deleted_at, nonce_created_at, scopes, access_token_expires_at, refresh_token, refresh_token_expires_at FROM stores WHERE app = 'default';
+
+DROP TABLE stores;
+ALTER TABLE stores_old RENAME TO stores;
+
+CREATE INDEX idx_stores_deleted_at ON stores (deleted_at);
+CREATE INDEX idx_stores_name ON stores (name);
\ No newline at end of file
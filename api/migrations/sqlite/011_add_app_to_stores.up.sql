@@ -0,0 +1,33 @@
+-- Key stores and webhook jobs by app, records created before belong to the default app
+-- SQLite can't drop unique constraint of stores.name, so stores table is rebuilt
+CREATE TABLE stores_new (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+    app TEXT NOT NULL DEFAULT 'default',
+    name TEXT NOT NULL,
+    nonce TEXT,
+    access_token TEXT,
+    installed INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    deleted_at DATETIME,
+    nonce_created_at DATETIME,
+    scopes TEXT NOT NULL DEFAULT '',
+    access_token_expires_at DATETIME,
+    refresh_token TEXT NOT NULL DEFAULT '',
+    refresh_token_expires_at DATETIME
+);
+
+INSERT INTO stores_new (id, name, nonce, access_token, installed, created_at, updated_at, deleted_at, nonce_created_at, scopes, access_token_expires_at, refresh_token, refresh_token_expires_at)
+SELECT id, name, nonce, access_token, installed, created_at, updated_at, deleted_at, nonce_created_at, scopes, access_token_expires_at, refresh_token, refresh_token_expires_at FROM stores;
+
+DROP TABLE stores;
+ALTER TABLE stores_new RENAME TO stores;
+
+CREATE INDEX idx_stores_deleted_at ON stores (deleted_at);
+CREATE INDEX idx_stores_name ON stores (name);
+
+-- Only one not deleted store per app and shop, deleted stores can be installed again
+CREATE UNIQUE INDEX idx_stores_app_name ON stores (app, name) WHERE deleted_at IS NULL;
+
+ALTER TABLE webhook_jobs ADD COLUMN app TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_dead_letters ADD COLUMN app TEXT NOT NULL DEFAULT 'default';
\ No newline at end of file
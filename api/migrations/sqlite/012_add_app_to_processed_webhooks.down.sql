@@ -0,0 +1 @@
+ALTER TABLE processed_webhooks DROP COLUMN app;
\ No newline at end of file
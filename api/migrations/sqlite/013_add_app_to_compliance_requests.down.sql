@@ -0,0 +1 @@
+ALTER TABLE compliance_requests DROP COLUMN app;
\ No newline at end of file
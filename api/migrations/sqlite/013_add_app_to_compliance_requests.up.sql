@@ -0,0 +1,2 @@
+-- Key compliance requests by app, records created before belong to the default app
+ALTER TABLE compliance_requests ADD COLUMN app TEXT NOT NULL DEFAULT 'default';
\ No newline at end of file
@@ -0,0 +1,212 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks values encrypted by Keyring, values without it are treated as plaintext.
+const prefix = "enc:v1:"
+
+// keySize is the size of both key encryption keys and data encryption keys, AES-256 is used.
+const keySize = 32
+
+var (
+	// ErrUnknownKey is returned when value is encrypted with a key which is not in the keyring.
+	ErrUnknownKey = errors.New("value is encrypted with unknown key")
+	// ErrMalformedCiphertext is returned when encrypted value can't be parsed or authenticated.
+	ErrMalformedCiphertext = errors.New("malformed ciphertext")
+)
+
+// Keyring encrypts values with envelope encryption using AES-GCM.
+// Every value is encrypted with a random data key, which is encrypted (wrapped) with the active key.
+// Encrypted values record ID of the key that wrapped their data key, so keys can be rotated:
+// new values are encrypted with the active key, while the other keys are used to decrypt old values only.
+//
+// Encrypted value has the format enc:v1:<key id>:<wrapped data key>:<ciphertext>,
+// where data key and ciphertext are base64 encoded and prepended with their GCM nonces.
+type Keyring struct {
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+}
+
+// NewKeyring creates keyring from comma separated list of <key id>:<base64 encoded 32 bytes key> pairs.
+// The first key is the active one.
+func NewKeyring(rawKeys string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+
+	for _, pair := range strings.Split(rawKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		keyID, rawKey, ok := strings.Cut(pair, ":")
+		if !ok || keyID == "" || strings.Contains(keyID, ":") {
+			return nil, errors.New("key has to be in <key id>:<base64 key> format")
+		}
+		if _, ok := k.keys[keyID]; ok {
+			return nil, fmt.Errorf("key %q is duplicated", keyID)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not base64 encoded: %w", keyID, err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q has to be %d bytes long", keyID, keySize)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", keyID, err)
+		}
+		k.keys[keyID] = aead
+
+		if k.activeKeyID == "" {
+			k.activeKeyID = keyID
+		}
+	}
+
+	if k.activeKeyID == "" {
+		return nil, errors.New("no keys provided")
+	}
+
+	return k, nil
+}
+
+// ActiveKeyID returns ID of the key used to encrypt new values.
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeKeyID
+}
+
+// Encrypt encrypts value with a new data key wrapped with the active key.
+// Empty value is returned as is.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dataKey := make([]byte, keySize)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	// Key ID is authenticated along with the data key, so it can't be swapped
+	wrappedKey, err := seal(k.keys[k.activeKeyID], dataKey, []byte(k.activeKeyID))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	ciphertext, err := seal(dataAEAD, []byte(plaintext), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt value: %w", err)
+	}
+
+	return prefix + k.activeKeyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts value encrypted by Encrypt.
+// Values that are not encrypted are returned as is, so plaintext values written before encryption was enabled can be read.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformedCiphertext
+	}
+	keyID := parts[0]
+
+	keyAEAD, ok := k.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformedCiphertext
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformedCiphertext
+	}
+
+	dataKey, err := open(keyAEAD, wrappedKey, []byte(keyID))
+	if err != nil {
+		return "", ErrMalformedCiphertext
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", ErrMalformedCiphertext
+	}
+
+	plaintext, err := open(dataAEAD, ciphertext, nil)
+	if err != nil {
+		return "", ErrMalformedCiphertext
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value is not encrypted or encrypted with a key other than the active one.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	return !strings.HasPrefix(value, prefix+k.activeKeyID+":")
+}
+
+// IsEncrypted reports whether value is encrypted by Keyring.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// seal encrypts plaintext and prepends random nonce to the result.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts ciphertext created by seal.
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrMalformedCiphertext
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,255 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey returns base64 encoded key of keySize bytes filled with b.
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), keySize)))
+}
+
+func newTestKeyring(t *testing.T, rawKeys string) *Keyring {
+	t.Helper()
+
+	k, err := NewKeyring(rawKeys)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	return k
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawKeys     string
+		wantActive  string
+		wantErrText string
+	}{
+		{
+			name:       "single key",
+			rawKeys:    "k1:" + testKey(1),
+			wantActive: "k1",
+		},
+		{
+			name:       "first key is active",
+			rawKeys:    " k2:" + testKey(2) + " , k1:" + testKey(1) + ",",
+			wantActive: "k2",
+		},
+		{
+			name:        "no keys",
+			rawKeys:     " , ",
+			wantErrText: "no keys provided",
+		},
+		{
+			name:        "missing key id",
+			rawKeys:     testKey(1),
+			wantErrText: "format",
+		},
+		{
+			name:        "empty key id",
+			rawKeys:     ":" + testKey(1),
+			wantErrText: "format",
+		},
+		{
+			name:        "duplicate key id",
+			rawKeys:     "k1:" + testKey(1) + ",k1:" + testKey(2),
+			wantErrText: "duplicated",
+		},
+		{
+			name:        "key is not base64",
+			rawKeys:     "k1:not-base64!",
+			wantErrText: "not base64",
+		},
+		{
+			name:        "short key",
+			rawKeys:     "k1:" + base64.StdEncoding.EncodeToString(make([]byte, 16)),
+			wantErrText: "32 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := NewKeyring(tt.rawKeys)
+			if tt.wantErrText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Fatalf("error = %v, want error containing %q", err, tt.wantErrText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if k.ActiveKeyID() != tt.wantActive {
+				t.Errorf("active key = %q, want %q", k.ActiveKeyID(), tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	k := newTestKeyring(t, "k1:"+testKey(1))
+
+	encrypted, err := k.Encrypt("shpat_secret")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if !IsEncrypted(encrypted) || strings.Contains(encrypted, "shpat_secret") {
+		t.Fatalf("value is not encrypted: %q", encrypted)
+	}
+	if !strings.HasPrefix(encrypted, prefix+"k1:") {
+		t.Errorf("value doesn't record active key: %q", encrypted)
+	}
+
+	again, err := k.Encrypt("shpat_secret")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if again == encrypted {
+		t.Error("values are encrypted deterministically")
+	}
+
+	decrypted, err := k.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if decrypted != "shpat_secret" {
+		t.Errorf("decrypted = %q, want %q", decrypted, "shpat_secret")
+	}
+	if k.NeedsRotation(encrypted) {
+		t.Error("value encrypted with the active key needs rotation")
+	}
+}
+
+func TestKeyringEmptyValue(t *testing.T) {
+	k := newTestKeyring(t, "k1:"+testKey(1))
+
+	encrypted, err := k.Encrypt("")
+	if err != nil || encrypted != "" {
+		t.Fatalf("Encrypt(\"\") = %q, %v, want empty value", encrypted, err)
+	}
+	if k.NeedsRotation("") {
+		t.Error("empty value needs rotation")
+	}
+}
+
+func TestKeyringPlaintextPassthrough(t *testing.T) {
+	k := newTestKeyring(t, "k1:"+testKey(1))
+
+	decrypted, err := k.Decrypt("shpat_plaintext")
+	if err != nil {
+		t.Fatalf("failed to decrypt plaintext: %v", err)
+	}
+	if decrypted != "shpat_plaintext" {
+		t.Errorf("decrypted = %q, want %q", decrypted, "shpat_plaintext")
+	}
+	if !k.NeedsRotation("shpat_plaintext") {
+		t.Error("plaintext value doesn't need rotation")
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKeyring := newTestKeyring(t, "k1:"+testKey(1))
+	encrypted, err := oldKeyring.Encrypt("shpat_secret")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	k := newTestKeyring(t, "k2:"+testKey(2)+",k1:"+testKey(1))
+	if !k.NeedsRotation(encrypted) {
+		t.Fatal("value encrypted with old key doesn't need rotation")
+	}
+
+	decrypted, err := k.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("failed to decrypt with old key: %v", err)
+	}
+	reencrypted, err := k.Encrypt(decrypted)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if k.NeedsRotation(reencrypted) {
+		t.Error("re-encrypted value needs rotation")
+	}
+
+	// Old key can be removed once values are re-encrypted
+	newKeyring := newTestKeyring(t, "k2:"+testKey(2))
+	decrypted, err = newKeyring.Decrypt(reencrypted)
+	if err != nil || decrypted != "shpat_secret" {
+		t.Errorf("Decrypt() = %q, %v, want %q", decrypted, err, "shpat_secret")
+	}
+}
+
+func TestKeyringUnknownKey(t *testing.T) {
+	encrypted, err := newTestKeyring(t, "k1:"+testKey(1)).Encrypt("shpat_secret")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	_, err = newTestKeyring(t, "k2:"+testKey(2)).Decrypt(encrypted)
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestKeyringTampered(t *testing.T) {
+	// Both key IDs have the same key, so swapped key ID is detected only because it's authenticated
+	k := newTestKeyring(t, "k1:"+testKey(1)+",k2:"+testKey(1))
+	encrypted, err := k.Encrypt("shpat_secret")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, prefix), ":")
+
+	// flip changes the last byte of base64 encoded part
+	flip := func(part string) string {
+		raw, err := base64.RawStdEncoding.DecodeString(part)
+		if err != nil {
+			t.Fatalf("failed to decode part: %v", err)
+		}
+		raw[len(raw)-1] ^= 1
+		return base64.RawStdEncoding.EncodeToString(raw)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{
+			name:  "key id",
+			value: prefix + "k2:" + parts[1] + ":" + parts[2],
+		},
+		{
+			name:  "wrapped data key",
+			value: prefix + parts[0] + ":" + flip(parts[1]) + ":" + parts[2],
+		},
+		{
+			name:  "ciphertext",
+			value: prefix + parts[0] + ":" + parts[1] + ":" + flip(parts[2]),
+		},
+		{
+			name:  "truncated ciphertext",
+			value: prefix + parts[0] + ":" + parts[1] + ":AAAA",
+		},
+		{
+			name:  "missing part",
+			value: prefix + parts[0] + ":" + parts[1],
+		},
+		{
+			name:  "invalid base64",
+			value: prefix + parts[0] + ":" + parts[1] + ":%%%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := k.Decrypt(tt.value)
+			if !errors.Is(err, ErrMalformedCiphertext) {
+				t.Errorf("error = %v, want %v", err, ErrMalformedCiphertext)
+			}
+		})
+	}
+}
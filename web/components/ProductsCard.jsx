@@ -2,7 +2,9 @@ import { useState } from "react";
 import {
   Card,
   BlockStack,
+  InlineStack,
   Text,
+  TextField,
 } from "@shopify/polaris";
 import { useAppBridge } from "@shopify/app-bridge-react";
 import { useAppQuery } from "../hooks";
@@ -10,6 +12,8 @@ import { useAppQuery } from "../hooks";
 export function ProductsCard() {
   const app = useAppBridge();
   const [isLoading, setIsLoading] = useState(false);
+  const [title, setTitle] = useState("");
+  const [price, setPrice] = useState("10.00");
 
   const {
     data,
@@ -26,7 +30,7 @@ export function ProductsCard() {
   });
 
 
-  const handlePopulate = async () => {
+  const handleCreate = async () => {
     setIsLoading(true);
     try {
       // Get session token from URL parameters (same as useAppQuery)
@@ -41,27 +45,36 @@ export function ProductsCard() {
         authHeaders['Authorization'] = `Bearer ${session}`;
       }
 
-      const response = await fetch("/api/products/create", {
-        method: "GET",
+      const response = await fetch("/api/products", {
+        method: "POST",
         headers: {
           ...authHeaders,
           "Content-Type": "application/json"
-        }
+        },
+        body: JSON.stringify({
+          title,
+          variants: [{ price }],
+        }),
       });
 
       if (response.ok) {
+        setTitle("");
         await refetchProductCount();
         if (app) {
-          app.toast.show("5 products created!");
+          app.toast.show("Product created!");
         }
       } else {
+        // Invalid fields are returned as validationErrors
+        const body = await response.json().catch(() => ({}));
+        const reasons = Object.entries(body.validationErrors ?? {})
+          .map(([field, reason]) => `${field} ${reason}`);
         if (app) {
-          app.toast.show("There was an error creating products", { isError: true });
+          app.toast.show(reasons.join(", ") || "There was an error creating product", { isError: true });
         }
       }
     } catch (error) {
       if (app) {
-        app.toast.show("There was an error creating products", { isError: true });
+        app.toast.show("There was an error creating product", { isError: true });
       }
     } finally {
       setIsLoading(false);
@@ -75,7 +88,7 @@ export function ProductsCard() {
           Product Counter
         </Text>
         <Text as="p">
-          Sample products are created with a default title and price. You can
+          Products are created with the given title and price. You can
           remove them at any time.
         </Text>
         <div style={{ textAlign: 'center' }}>
@@ -88,20 +101,35 @@ export function ProductsCard() {
                 {isLoadingCount || !data ? "-" : (data?.count ?? 0)}
               </Text>
             </BlockStack>
+            <InlineStack gap="200" align="center" blockAlign="end">
+              <TextField
+                label="Title"
+                value={title}
+                onChange={setTitle}
+                autoComplete="off"
+              />
+              <TextField
+                label="Price"
+                type="number"
+                value={price}
+                onChange={setPrice}
+                autoComplete="off"
+              />
+            </InlineStack>
             <button
-              onClick={handlePopulate}
-              disabled={isLoading || isLoadingCount}
+              onClick={handleCreate}
+              disabled={isLoading || isLoadingCount || !title.trim()}
               style={{
                 padding: '12px 20px',
                 backgroundColor: '#008060',
                 color: 'white',
                 border: 'none',
                 borderRadius: '6px',
-                cursor: isLoading || isLoadingCount ? 'not-allowed' : 'pointer',
-                opacity: isLoading || isLoadingCount ? 0.6 : 1,
+                cursor: isLoading || isLoadingCount || !title.trim() ? 'not-allowed' : 'pointer',
+                opacity: isLoading || isLoadingCount || !title.trim() ? 0.6 : 1,
               }}
             >
-              {isLoading ? 'Creating...' : 'Populate 5 products'}
+              {isLoading ? 'Creating...' : 'Create product'}
             </button>
           </BlockStack>
         </div>
@@ -45,6 +45,18 @@ export const useAppQuery = ({ url, fetchInit = {}, reactQueryOptions }) => {
       });
       clearTimeout(timeoutId);
 
+      // Store has to grant new access scopes, authorization happens outside of the admin iframe
+      if (
+        response.status === 401 &&
+        response.headers.get("X-Shopify-API-Request-Failure-Reauthorize") === "1"
+      ) {
+        const reauthorizeUrl = response.headers.get(
+          "X-Shopify-API-Request-Failure-Reauthorize-Url"
+        );
+        window.open(reauthorizeUrl, "_top");
+        return null;
+      }
+
       if (!response.ok) {
         const errorText = await response.text();
         throw new Error(`HTTP error! status: ${response.status} - ${errorText}`);